@@ -0,0 +1,148 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// decodeFrame is an object or array on the stack of decodeValid, key is the key of the next object member
+type decodeFrame struct {
+	object bool
+	m      map[string]any
+	sl     []any
+	key    string
+	// p is the pooled slice of the array, it's updated by the grown items once the array is decoded
+	p *[]any
+}
+
+// decodeValid decodes the valid JSON document into the same values as json.Decoder with UseNumber does,
+// strings without escapes and numbers reference the document. The decoding is iterative, maps and slices
+// are got from pv if it's set
+func decodeValid(s string, pv *pooledValues) any {
+	var (
+		buf   [16]decodeFrame
+		stack = buf[:0]
+		pos   int
+	)
+
+	for {
+		var val any
+
+		pos = skipSpaces(s, pos)
+		switch c := s[pos]; c {
+		case '{':
+			fr := decodeFrame{object: true, m: pv.newMap()}
+			if pos = skipSpaces(s, pos+1); s[pos] == '}' {
+				val, pos = fr.m, pos+1
+				break
+			}
+
+			fr.key, pos = decodeKey(s, pos)
+			stack = append(stack, fr)
+			continue
+		case '[':
+			fr := decodeFrame{}
+			fr.sl, fr.p = pv.newSlice()
+			if pos = skipSpaces(s, pos+1); s[pos] == ']' {
+				val, pos = fr.sl, pos+1
+				break
+			}
+
+			stack = append(stack, fr)
+			continue
+		case '"':
+			val, pos = decodeString(s, pos)
+		case 't':
+			val, pos = true, pos+len("true")
+		case 'f':
+			val, pos = false, pos+len("false")
+		case 'n':
+			val, pos = nil, pos+len("null")
+		default:
+			end := pos + 1
+			for end < len(s) && isNumberChar(s[end]) {
+				end++
+			}
+			val, pos = json.Number(s[pos:end]), end
+		}
+
+		// the value is added to the parent, the parents ending after it are closed
+		for {
+			if len(stack) == 0 {
+				return val
+			}
+
+			fr := &stack[len(stack)-1]
+			if fr.object {
+				fr.m[fr.key] = val
+			} else {
+				fr.sl = append(fr.sl, val)
+			}
+
+			pos = skipSpaces(s, pos)
+			if s[pos] == ',' {
+				if fr.object {
+					fr.key, pos = decodeKey(s, skipSpaces(s, pos+1))
+				} else {
+					pos++
+				}
+				break
+			}
+
+			pos++ // } or ]
+			if fr.object {
+				val = fr.m
+			} else {
+				if fr.p != nil {
+					*fr.p = fr.sl
+				}
+				val = fr.sl
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// decodeKey decodes the object key at pos and skips the colon after it, returns the offset of the member value
+func decodeKey(s string, pos int) (string, int) {
+	key, pos := decodeString(s, pos)
+	return key, skipSpaces(s, pos) + 1
+}
+
+// decodeString decodes the string at pos, returns the offset after it. Strings with escapes or invalid
+// UTF-8 (replaced with U+FFFD) are decoded by encoding/json, the others reference the document
+func decodeString(s string, pos int) (string, int) {
+	end := pos + 1
+	escaped, ascii := false, true
+	for ; s[end] != '"'; end++ {
+		switch {
+		case s[end] == '\\':
+			escaped = true
+			end++ // the escaped character
+		case s[end] >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+
+	if raw := s[pos+1 : end]; !escaped && (ascii || utf8.ValidString(raw)) {
+		return raw, end + 1
+	}
+
+	var str string
+	_ = json.Unmarshal([]byte(s[pos:end+1]), &str) // the document is valid
+	return str, end + 1
+}
+
+// skipSpaces returns the offset of the first character after JSON whitespace from pos
+func skipSpaces(s string, pos int) int {
+	for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t' || s[pos] == '\r' || s[pos] == '\n') {
+		pos++
+	}
+
+	return pos
+}
+
+// isNumberChar check character on being a part of JSON number
+func isNumberChar(c byte) bool {
+	return c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
@@ -0,0 +1,38 @@
+package jsonmask
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "should decode primitive values", value: ` [true, false, null, "a", 0, -1.5e+3, 12345678901234567890] `},
+		{name: "should decode nested objects and arrays", value: `{"a":{"b":[{"c":[]},{}]},"d":[[1],[2,3]],"e":{}}`},
+		{name: "should decode escaped strings", value: `{"a\"b":"c\\d\nA😀","e":"\/"}`},
+		{name: "should decode utf-8 strings", value: `{"ключ":"значение","emoji":"😀"}`},
+		{name: "should replace invalid utf-8", value: "{\"a\":\"\xff\xfe\",\"b\xff\":1}"},
+		{name: "should keep the last of duplicate keys", value: `{"a":1,"a":2}`},
+		{name: "should decode document with whitespace", value: "\n{ \"a\" :\t[ 1 , 2 ] ,\r\n\"b\" : { } }\n"},
+		{name: "should decode primitive root value", value: `"root"`},
+		{name: "should decode number root value", value: `1e400`},
+		{name: "should decode deeply nested arrays", value: strings.Repeat(`[`, 1000) + strings.Repeat(`]`, 1000)},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			var expect any
+			if err := newDecoder([]byte(tt.value)).Decode(&expect); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			if got := decodeValid(tt.value, nil); !reflect.DeepEqual(got, expect) {
+				t.Errorf("Process() got = %#v, want %#v", got, expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package jsonmask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// MaskWithDigest method for masking JSON value like Mask and returning the hex digest of the masked output,
+// it's SHA-256 unless another hash is set by WithDigestHash
+func (j *JsonMask) MaskWithDigest(value string) (string, string, error) {
+	res, err := j.Mask(value)
+	if err != nil {
+		return "", "", err
+	}
+
+	newHash := j.digestHash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+
+	h := newHash()
+	if _, err := io.WriteString(h, res); err != nil {
+		return "", "", err
+	}
+
+	return res, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,71 @@
+package jsonmask
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"testing"
+)
+
+func TestMaskWithDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		newHash func() hash.Hash
+		value   string
+		expect  string
+	}{
+		{
+			name:    "should return sha256 digest of masked output",
+			opts:    []Option{WithFields("ssn")},
+			newHash: sha256.New,
+			value:   `{"ssn":"123","name":"bob"}`,
+			expect:  `{"name":"bob","ssn":"***"}`,
+		},
+		{
+			name:    "should return digest of unchanged output",
+			opts:    []Option{WithFields("ssn")},
+			newHash: sha256.New,
+			value:   `{"name": "bob"}`,
+			expect:  `{"name": "bob"}`,
+		},
+		{
+			name:    "should return digest by configured hash",
+			opts:    []Option{WithFields("ssn"), WithDigestHash(sha512.New)},
+			newHash: sha512.New,
+			value:   `{"ssn":"123"}`,
+			expect:  `{"ssn":"***"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMaskWithOptions(tt.opts...)
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, digest, err := mask.MaskWithDigest(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+
+			h := tt.newHash()
+			h.Write([]byte(tt.expect))
+			if want := hex.EncodeToString(h.Sum(nil)); digest != want {
+				t.Errorf("Process() digest = %v, want %v", digest, want)
+			}
+		})
+	}
+}
+
+func TestMaskWithDigestInvalid(t *testing.T) {
+	mask := NewJSONMask("ssn")
+
+	if _, digest, err := mask.MaskWithDigest(`{"ssn":`); err == nil || digest != "" {
+		t.Errorf("Process() digest = %v, error = %v, want error", digest, err)
+	}
+}
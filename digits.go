@@ -0,0 +1,94 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// maxDigitArrayLen is the max number of digits of WithDigitArrayFields arrays fitting into int64
+const maxDigitArrayLen = 18
+
+// maskDigitArray method for masking the array of single digits by xpath fk as a whole number ([1,2,3,4] -> 1234)
+// by the integer func, the masked number is written back as digits left-padded with zeros to the original length.
+// Returns false if the array isn't a digit array or there is no integer func
+func (j *JsonMask) maskDigitArray(st *state, fk string, sl []any) (any, bool, error) {
+	num, ok := assembleDigits(sl)
+	if !ok {
+		return sl, false, nil
+	}
+
+	var (
+		r   int64
+		err error
+	)
+
+	int64Fn, intFn := j.integerFuncs(fk)
+	switch {
+	case int64Fn != nil:
+		r, err = int64Fn(fk, num)
+	case intFn != nil && num <= math.MaxInt:
+		var ri int
+		ri, err = intFn(fk, int(num))
+		r = int64(ri)
+	default:
+		return sl, false, nil
+	}
+
+	if errors.Is(err, ErrSkip) {
+		return sl, true, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	if r < 0 {
+		return nil, true, fmt.Errorf("%s: masked digit array number %d is negative", rootPath(fk), r)
+	}
+
+	digits := strconv.FormatInt(r, 10)
+	for len(digits) < len(sl) {
+		digits = "0" + digits
+	}
+
+	res := make([]any, len(digits))
+	for i, d := range digits {
+		res[i] = float64(d - '0')
+	}
+
+	st.changed = true
+	return res, true, nil
+}
+
+// assembleDigits returns the number of the array of single digits, false for other arrays
+func assembleDigits(sl []any) (int64, bool) {
+	if len(sl) == 0 || len(sl) > maxDigitArrayLen {
+		return 0, false
+	}
+
+	var num int64
+	for _, item := range sl {
+		var d float64
+		switch v := item.(type) {
+		case float64:
+			d = v
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return 0, false
+			}
+			d = f
+		default:
+			return 0, false
+		}
+
+		if d < 0 || d > 9 || d != math.Trunc(d) {
+			return 0, false
+		}
+
+		num = num*10 + int64(d)
+	}
+
+	return num, true
+}
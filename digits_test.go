@@ -0,0 +1,93 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWithDigitArrayFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		mask     *JsonMask
+		register func(m *JsonMask)
+		value    string
+		expect   string
+		wantErr  bool
+	}{
+		{
+			name: "should mask digit array as a whole number",
+			mask: NewJSONMaskWithOptions(WithDigitArrayFields("/id")),
+			register: func(m *JsonMask) {
+				m.RegisterMaskInt64Func(func(_ string, v int64) (int64, error) { return v + 1111, nil })
+			},
+			value:  `{"id":[1,2,3,4],"other":[1,2]}`,
+			expect: `{"id":[2,3,4,5],"other":[1,2]}`,
+		},
+		{
+			name:     "should pad masked number with zeros to the original length",
+			mask:     NewJSONMaskWithOptions(WithDigitArrayFields("/id")),
+			register: func(m *JsonMask) { m.RegisterMaskIntFunc(func(_ string, v int) (int, error) { return v / 100, nil }) },
+			value:    `{"id":[0,1,2,3,4]}`,
+			expect:   `{"id":[0,0,0,1,2]}`,
+		},
+		{
+			name:     "should write all digits of longer masked number",
+			mask:     NewJSONMaskWithOptions(WithDigitArrayFields("/list/id"), WithIndexAgnosticPaths()),
+			register: func(m *JsonMask) { m.RegisterMaskIntFunc(func(_ string, v int) (int, error) { return v * 10, nil }) },
+			value:    `{"list":[{"id":[9,9]}]}`,
+			expect:   `{"list":[{"id":[9,9,0]}]}`,
+		},
+		{
+			name:     "should keep array with non-digit items",
+			mask:     NewJSONMaskWithOptions(WithDigitArrayFields("/a", "/b", "/c", "/d")),
+			register: func(m *JsonMask) { m.RegisterMaskIntFunc(testMaskRandomInt(5)) },
+			value:    `{"a":[1,10],"b":[1,"2"],"c":[1.5],"d":[]}`,
+			expect:   `{"a":[1,10],"b":[1,"2"],"c":[1.5],"d":[]}`,
+		},
+		{
+			name:     "should mask digit array with verbatim copy",
+			mask:     NewJSONMaskWithOptions(WithDigitArrayFields("/id"), WithCopyUnmatchedVerbatim()),
+			register: func(m *JsonMask) { m.RegisterMaskIntFunc(testMaskRandomInt(7)) },
+			value:    `{"name": "bob", "id": [1, 2]}`,
+			expect:   `{"name": "bob", "id": [0,7]}`,
+		},
+		{
+			name:     "should return error on negative masked number",
+			mask:     NewJSONMaskWithOptions(WithDigitArrayFields("/id")),
+			register: func(m *JsonMask) { m.RegisterMaskIntFunc(testMaskRandomInt(-1)) },
+			value:    `{"id":[1]}`,
+			wantErr:  true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.register(tt.mask)
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestAssembleDigits(t *testing.T) {
+	num, ok := assembleDigits([]any{float64(4), float64(0), float64(2)})
+	if !ok || num != 402 {
+		t.Errorf("Process() got = %v, %v, want %v", num, ok, 402)
+	}
+
+	if _, ok := assembleDigits(make([]any, maxDigitArrayLen+1)); ok {
+		t.Errorf("Process() got = %v, want too long array rejected", ok)
+	}
+
+	mask := NewJSONMaskWithOptions(WithDigitArrayFields("/id"))
+	mask.RegisterMaskIntFunc(func(_ string, _ int) (int, error) { return 0, ErrSkip })
+	if got, err := mask.Mask(`{"id":[1,2]}`); err != nil || got != `{"id":[1,2]}` {
+		t.Errorf("Process() got = %v, %v, want unchanged array on ErrSkip", got, err)
+	}
+}
@@ -0,0 +1,67 @@
+package jsonmask
+
+// discriminatorRule is a list of fields masked in objects having the discriminator key with the value
+type discriminatorRule struct {
+	key    string
+	value  string
+	fields map[string]struct{}
+	// siblings masks all members of the object except the discriminator key by WithClassificationRule
+	siblings bool
+}
+
+// discriminatorTargets method for getting keys of the object members masked by WithDiscriminatorRule
+// and WithClassificationRule, returns nil if no rule matches
+func (j *JsonMask) discriminatorTargets(members []orderedMember) map[string]struct{} {
+	var targets map[string]struct{}
+	for _, m := range members {
+		val, ok := m.value.(string)
+		if !ok {
+			continue
+		}
+
+		key := j.fold(m.key)
+		for _, rule := range j.discriminators {
+			if key != rule.key || val != rule.value {
+				continue
+			}
+
+			if targets == nil {
+				targets = make(map[string]struct{}, len(rule.fields))
+			}
+
+			for field := range rule.fields {
+				targets[field] = struct{}{}
+			}
+
+			if rule.siblings {
+				for _, sibling := range members {
+					if name := j.fold(sibling.key); name != key {
+						targets[name] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+
+	return targets
+}
+
+// objectTargets method for getting keys of the decoded object masked by WithDiscriminatorRule and WithClassificationRule
+func (j *JsonMask) objectTargets(val any) map[string]struct{} {
+	if len(j.discriminators) == 0 {
+		return nil
+	}
+
+	switch v := val.(type) {
+	case map[string]any:
+		members := make([]orderedMember, 0, len(v))
+		for k, m := range v {
+			members = append(members, orderedMember{key: k, value: m})
+		}
+		return j.discriminatorTargets(members)
+	case orderedObject:
+		return j.discriminatorTargets(v)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package jsonmask
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadFields reads the list of global and xpath fields, one per line,
+// surrounding whitespace is trimmed, blank lines and lines starting with # are skipped
+func LoadFields(r io.Reader) ([]string, error) {
+	var fields []string
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		field := strings.TrimSpace(sc.Text())
+		if field == "" || strings.HasPrefix(field, "#") {
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read fields: %w", err)
+	}
+
+	return fields, nil
+}
+
+// NewJSONMaskFromReader initializes a JsonMask with the fields read by LoadFields,
+// the same way as NewJSONMask does, other configuration is passed by options
+func NewJSONMaskFromReader(r io.Reader, opts ...Option) (*JsonMask, error) {
+	fields, err := LoadFields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONMaskWithOptions(append([]Option{WithFields(fields...)}, opts...)...), nil
+}
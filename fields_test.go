@@ -0,0 +1,67 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLoadFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		expect  []string
+		wantErr bool
+	}{
+		{
+			name:    "should skip comments and blank lines",
+			value:   "# global fields\nname\n\n  token  \n\t# xpath fields\n/user/ssn\r\n/list[1]\n",
+			expect:  []string{"name", "token", "/user/ssn", "/list[1]"},
+			wantErr: false,
+		},
+		{
+			name:    "should return no fields for comments only",
+			value:   "# nothing\n\n",
+			expect:  nil,
+			wantErr: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := LoadFields(strings.NewReader(tt.value))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestNewJSONMaskFromReader(t *testing.T) {
+	mask, err := NewJSONMaskFromReader(strings.NewReader("# fields\nname\n /user/ssn \n"), WithSuffixFields("_id"))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	got, err := mask.Mask(`{"name": "a", "ssn": "b", "user": {"ssn": "c", "org_id": "d"}}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"name":"*","ssn":"b","user":{"org_id":"*","ssn":"*"}}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+
+	errRead := errors.New("read")
+	if _, err := NewJSONMaskFromReader(iotest.ErrReader(errRead)); !errors.Is(err, errRead) {
+		t.Errorf("Process() error = %v, want %v", err, errRead)
+	}
+}
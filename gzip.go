@@ -0,0 +1,65 @@
+package jsonmask
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// maxGzipSize is the default limit of the decompressed size of MaskGzipBase64 values
+const maxGzipSize = 10 << 20
+
+// MaskGzipBase64 masks base64-encoded gzip of JSON with the inner JsonMask, the masked JSON is gzipped
+// and base64-encoded back. Values that can't be decoded, decompressed or masked as JSON are left unchanged.
+// maxSize limits the decompressed size (10 MiB by default) so a small value can't expand into a huge one,
+// masking fails with ErrGzipTooLarge for larger values
+func MaskGzipBase64(inner *JsonMask, maxSize ...int64) MaskStringFunc {
+	limit := int64(maxGzipSize)
+	if len(maxSize) > 0 {
+		limit = maxSize[0]
+	}
+
+	if limit < 0 {
+		limit = 0
+	}
+
+	return func(path, val string) (string, error) {
+		compressed, err := base64.StdEncoding.DecodeString(val)
+		if err != nil {
+			return val, ErrSkip
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return val, ErrSkip
+		}
+
+		// one byte over the limit tells the value exceeding it from the one of the exact size
+		raw, err := io.ReadAll(io.LimitReader(zr, limit+1))
+		if err != nil {
+			return val, ErrSkip
+		}
+
+		if int64(len(raw)) > limit {
+			return "", fmt.Errorf("%s: %w: over %d bytes", path, ErrGzipTooLarge, limit)
+		}
+
+		masked, err := inner.MaskBytes(raw)
+		if err != nil || bytes.Equal(masked, raw) {
+			return val, ErrSkip
+		}
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(masked); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+
+		return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+	}
+}
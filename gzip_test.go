@@ -0,0 +1,107 @@
+package jsonmask
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMaskGzipBase64(t *testing.T) {
+	inner := NewJSONMask("ssn")
+	inner.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	mask := NewJSONMask("payload", "other", "plain")
+	mask.RegisterMaskStringFunc(MaskGzipBase64(inner))
+
+	value, err := json.Marshal(map[string]string{
+		"payload": testGzipBase64(t, `{"ssn": "123", "name": "bob"}`),
+		"other":   testGzipBase64(t, `{"name": "bob"}`),
+		"plain":   "not base64!",
+	})
+	if err != nil {
+		t.Fatalf("json marshal: %v", err)
+	}
+
+	got, err := mask.Mask(string(value))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var res map[string]string
+	if err := json.Unmarshal([]byte(got), &res); err != nil {
+		t.Fatalf("json unmarshal: %v", err)
+	}
+
+	if payload := testGunzipBase64(t, res["payload"]); payload != `{"name":"bob","ssn":"***"}` {
+		t.Errorf("Process() payload = %v, want masked inner json", payload)
+	}
+	if payload := testGunzipBase64(t, res["other"]); payload != `{"name": "bob"}` {
+		t.Errorf("Process() other = %v, want unchanged inner json", payload)
+	}
+	if res["plain"] != "not base64!" {
+		t.Errorf("Process() plain = %v, want unchanged", res["plain"])
+	}
+}
+
+func TestMaskGzipBase64Limit(t *testing.T) {
+	inner := NewJSONMask("ssn")
+	inner.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	value := `{"ssn": "123", "name": "bob"}`
+	tests := []struct {
+		name    string
+		fn      MaskStringFunc
+		value   string
+		wantErr error
+	}{
+		{name: "should mask value of the limit size", fn: MaskGzipBase64(inner, int64(len(value))), value: value},
+		{name: "should fail on value over the limit", fn: MaskGzipBase64(inner, int64(len(value)-1)), value: value, wantErr: ErrGzipTooLarge},
+		{name: "should fail on value over the default limit", fn: MaskGzipBase64(inner), value: `"` + strings.Repeat("a", maxGzipSize) + `"`, wantErr: ErrGzipTooLarge},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			_, err := tt.fn("/payload", testGzipBase64(t, tt.value))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func testGzipBase64(t *testing.T, val string) string {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(val)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func testGunzipBase64(t *testing.T, val string) string {
+	compressed, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		t.Fatalf("base64 decode: %v", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gzip read: %v", err)
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,54 @@
+package jsonmask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// MaskHMACString masks the string with hex of keyed HMAC-SHA256, unlike MaskHashString the hash of a known
+// value can't be computed without the key
+func MaskHMACString(key []byte) MaskStringFunc {
+	return func(_, val string) (string, error) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(val))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}
+}
+
+// MaskWithHMACIndex method for masking JSON value like Mask and returning a searchable index of the masked values,
+// it maps xpath of every masked string, number or boolean to MaskHMACString of its original value (numbers
+// and booleans by their JSON text), so a record could be found by the HMAC of a known plaintext
+func (j *JsonMask) MaskWithHMACIndex(value string, key []byte) (string, map[string]string, error) {
+	st := &state{originals: make(map[string]any)}
+	res, err := j.maskJSONString(st, value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hash := MaskHMACString(key)
+	index := make(map[string]string, len(st.originals))
+	for path, original := range st.originals {
+		var text string
+		switch v := original.(type) {
+		case string:
+			text = v
+		case json.Number:
+			text = v.String()
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			text = strconv.FormatBool(v)
+		default: // nulls and collapsed arrays
+			continue
+		}
+
+		if index[path], err = hash(path, text); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return res, index, nil
+}
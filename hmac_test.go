@@ -0,0 +1,73 @@
+package jsonmask
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMaskHMACString(t *testing.T) {
+	got, err := MaskHMACString([]byte("key"))("/ssn", "The quick brown fox jumps over the lazy dog")
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+
+	other, _ := MaskHMACString([]byte("other"))("/ssn", "The quick brown fox jumps over the lazy dog")
+	if other == got {
+		t.Errorf("Process() got = %v, want hash depending on the key", other)
+	}
+}
+
+func TestMaskWithHMACIndex(t *testing.T) {
+	key := []byte("secret")
+	hash := func(val string) string {
+		h, _ := MaskHMACString(key)("", val)
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+		index  map[string]string
+	}{
+		{
+			name:   "should index all masked fields",
+			mask:   NewJSONMask("ssn", "email", "/user/age", "/user/admin"),
+			value:  `{"ssn":"123","user":{"age":42,"admin":true,"emails":[{"email":"a@b.c"}],"name":"bob"}}`,
+			expect: `{"ssn":"***","user":{"admin":true,"age":1,"emails":[{"email":"*****"}],"name":"bob"}}`,
+			index:  map[string]string{"/ssn": hash("123"), "/user/age": hash("42"), "/user/emails[0]/email": hash("a@b.c")},
+		},
+		{
+			name:   "should return empty index for unmatched fields",
+			mask:   NewJSONMask("ssn"),
+			value:  `{"name":"bob"}`,
+			expect: `{"name":"bob"}`,
+			index:  map[string]string{},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskIntFunc(testMaskRandomInt(1))
+
+			got, index, err := tt.mask.MaskWithHMACIndex(tt.value, key)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+			if !reflect.DeepEqual(index, tt.index) {
+				t.Errorf("Process() index = %v, want %v", index, tt.index)
+			}
+		})
+	}
+}
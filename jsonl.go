@@ -0,0 +1,77 @@
+package jsonmask
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RecordError is an error of masking one record of MaskJSONL
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+// Error method for printing the record error with the line number
+func (e RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap method for getting the masking error of the record
+func (e RecordError) Unwrap() error {
+	return e.Err
+}
+
+// MaskJSONL method for masking newline-delimited JSON (one document per line) from r to w.
+// Records that can't be masked are not written and collected as RecordError with the line number
+// (starting from 1), the rest are still masked and written. The error is returned only for read/write failures
+func (j *JsonMask) MaskJSONL(r io.Reader, w io.Writer) ([]RecordError, error) {
+	var (
+		br      = bufio.NewReader(r)
+		bw      = bufio.NewWriter(w)
+		recErrs []RecordError
+	)
+
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return recErrs, fmt.Errorf("read: %w", err)
+		}
+
+		if len(b) == 0 {
+			break
+		}
+
+		doc := bytes.TrimRight(b, "\r\n")
+		out := doc
+		if len(bytes.TrimSpace(doc)) > 0 {
+			masked, mErr := j.MaskBytes(doc)
+			if mErr != nil {
+				recErrs = append(recErrs, RecordError{Line: line, Err: mErr})
+				continue
+			}
+
+			out = masked
+		}
+
+		if _, wErr := bw.Write(out); wErr != nil {
+			return recErrs, fmt.Errorf("write: %w", wErr)
+		}
+
+		if _, wErr := bw.Write(b[len(doc):]); wErr != nil {
+			return recErrs, fmt.Errorf("write: %w", wErr)
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return recErrs, fmt.Errorf("write: %w", err)
+	}
+
+	return recErrs, nil
+}
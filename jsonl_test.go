@@ -0,0 +1,55 @@
+package jsonmask
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMaskJSONL(t *testing.T) {
+	mask := NewJSONMask("password")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	input := strings.Join([]string{
+		`{"id": 1, "password": "secret"}`,
+		`{"id": 2, "password": `,
+		`{"id":3}`,
+		``,
+		`not json`,
+		`{"id": 4, "password": "pass"}`,
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	recErrs, err := mask.MaskJSONL(strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("MaskJSONL() error = %v", err)
+	}
+
+	expect := `{"id":1,"password":"******"}` + "\n" + `{"id":3}` + "\n\n" + `{"id":4,"password":"****"}` + "\n"
+	if out.String() != expect {
+		t.Errorf("MaskJSONL() got = %q, want %q", out.String(), expect)
+	}
+
+	if len(recErrs) != 2 || recErrs[0].Line != 2 || recErrs[1].Line != 5 {
+		t.Fatalf("MaskJSONL() record errors = %v, want lines 2 and 5", recErrs)
+	}
+	if !strings.HasPrefix(recErrs[0].Error(), "line 2: json unmarshal") {
+		t.Errorf("MaskJSONL() record error = %v, want unmarshal error of line 2", recErrs[0])
+	}
+}
+
+func TestMaskJSONLWithoutTrailingNewline(t *testing.T) {
+	mask := NewJSONMask("password")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	var out bytes.Buffer
+	recErrs, err := mask.MaskJSONL(strings.NewReader("{\"password\": \"a\"}\r\n{\"password\": \"bb\"}"), &out)
+	if err != nil || len(recErrs) != 0 {
+		t.Fatalf("MaskJSONL() error = %v, record errors = %v", err, recErrs)
+	}
+
+	expect := "{\"password\":\"*\"}\r\n{\"password\":\"**\"}"
+	if out.String() != expect {
+		t.Errorf("MaskJSONL() got = %q, want %q", out.String(), expect)
+	}
+}
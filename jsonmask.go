@@ -4,11 +4,21 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"math"
 	"math/rand"
+	"net/url"
+	gopath "path"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -20,48 +30,364 @@ const (
 	pathKey          = "/"
 	randomIntRange   = 1000
 	randomFloatRange = "1000.3"
+
+	maxRandomFloatDecimals = 15
+)
+
+var (
+	// ErrSkip is returned by mask funcs to leave the value unchanged, it isn't treated as an error
+	ErrSkip = errors.New("skip masking")
+	// ErrCycle is returned when decoded value passed to MaskAny references itself
+	ErrCycle = errors.New("cyclic reference")
+	// ErrInvalidPath is returned by Validate for malformed xpath fields
+	ErrInvalidPath = errors.New("invalid xpath")
+	// ErrNonFiniteFloat is returned when MaskFloat64Func produces NaN or Inf which can't be marshaled
+	ErrNonFiniteFloat = errors.New("non-finite float")
+	// ErrUnexpectedKey is returned when the root object has keys not expected by WithExpectedTopLevelKeys
+	ErrUnexpectedKey = errors.New("unexpected top-level key")
+	// ErrTypeMismatch is returned when a value by the xpath of WithPathType has another json type
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrInvalidRange is returned for malformed range of MaskRandomFloat64 or bounds of MaskIntRangeLabel
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrInvalidStrategy is returned for unknown or malformed strategy descriptors
+	ErrInvalidStrategy = errors.New("invalid strategy")
+	// ErrMaxArrayDepth is returned when arrays are nested deeper than WithMaxArrayDepth allows
+	ErrMaxArrayDepth = errors.New("max array depth exceeded")
+	// ErrValueTooLong is returned for string values longer than WithMaxValueLength with LongValueError policy
+	ErrValueTooLong = errors.New("value too long")
+	// ErrInvalidUTF8 is returned for invalid UTF-8 sequences with UTF8Error policy
+	ErrInvalidUTF8 = errors.New("invalid utf-8")
+	// ErrInvalidCharClass is returned by MaskCharClass funcs for unknown character classes
+	ErrInvalidCharClass = errors.New("invalid character class")
+	// ErrPostValidate is returned when the masked output is rejected by the hook of WithPostValidate
+	ErrPostValidate = errors.New("masked output validation failed")
+	// ErrInvalidNumberToken is returned when MaskNumberFunc produces a token which isn't a JSON number
+	ErrInvalidNumberToken = errors.New("invalid number token")
+	// ErrNumberRange is returned for the matched number out of float64 range or the whole number which doesn't
+	// fit into the registered integer func and there is no MaskFloat64Func to mask it
+	ErrNumberRange = errors.New("number out of range")
+	// ErrMatcherKind is returned for the kind of Matcher which is unknown or doesn't fit the value type
+	ErrMatcherKind = errors.New("invalid matcher kind")
+	// ErrKeyCollision is returned when a key masked by RegisterMaskKeyFunc equals another key of the same object
+	ErrKeyCollision = errors.New("masked key collision")
+	// ErrRedactedBlockKey is returned when the root object already has the key of WithRedactedBlock
+	// and masked values are moved into the block
+	ErrRedactedBlockKey = errors.New("redacted block key collision")
+	// ErrGzipTooLarge is returned by MaskGzipBase64 when the decompressed value exceeds the size limit
+	ErrGzipTooLarge = errors.New("decompressed gzip too large")
+	// ErrOriginalsDisabled is returned by MaskWithOriginals of JsonMask created without WithOriginalsCapture
+	ErrOriginalsDisabled = errors.New("originals capture is disabled")
 )
 
-// list of func type that must be satisfied to add a custom mask
+// list of func type that must be satisfied to add a custom mask,
+// MaskInt64Func is recommended over MaskIntFunc which can't hold large integers on 32-bit platforms
 type (
 	MaskStringFunc  func(path, value string) (string, error)
 	MaskIntFunc     func(path string, value int) (int, error)
+	MaskInt64Func   func(path string, value int64) (int64, error)
 	MaskFloat64Func func(path string, value float64) (float64, error)
+	MaskValueFunc   func(path string, value any) (any, error)
+	// MaskObjectFunc receives the object by the xpath and could rewrite several of its keys
+	MaskObjectFunc func(object map[string]any) error
+	// MaskRawStringFunc receives the raw JSON token of the string with quotes and escapes alongside the decoded value
+	MaskRawStringFunc func(path string, raw []byte, value string) (string, error)
+	// MaskNumberFunc receives the raw JSON token of the number and returns the replacement token
+	MaskNumberFunc func(path string, raw string) (string, error)
+	// MaskArrayFunc receives items of the array by the xpath and returns the transformed items,
+	// numbers are json.Number for decoded JSON
+	MaskArrayFunc func(path string, elems []any) ([]any, error)
+	// MaskAssignFunc is MaskStringFunc receiving the Assigner of the masking call for values depending
+	// on the values seen before, like MaskTokenize and MaskUnique
+	MaskAssignFunc func(a *Assigner, path, value string) (string, error)
 )
 
 // JsonMask is a struct that defines the masking process
 type JsonMask struct {
 	maskStringFunc  MaskStringFunc
+	maskAssignFunc  MaskAssignFunc
 	maskIntFunc     MaskIntFunc
+	maskInt64Func   MaskInt64Func
 	maskFloat64Func MaskFloat64Func
+	maskValueFunc   MaskValueFunc
+	maskKeyFunc     MaskStringFunc
+	maskRawFunc     MaskRawStringFunc
+	maskNumberFunc  MaskNumberFunc
+	matcher         Matcher
+	// keyScopes are xpath prefixes of WithKeyMaskScope, keys are masked everywhere if it's empty
+	keyScopes []string
+
+	defaultStringFunc MaskStringFunc
+
 	pathFields      map[string]struct{}
 	globalFields    map[string]struct{}
+	prefixFields    []string
+	suffixFields    []string
+	keyGlobs        []string
+	stringRules     []stringRule
+	clampNonFinite  bool
+	maskNulls       bool
+	nullPlaceholder any
+	caseInsensitive bool
+	indexAgnostic   bool
+	orderedKeys     bool
+	sortedKeys      bool
+	verbatim        bool
+	disabled        atomic.Bool
+	numericStrings  bool
+	decimalSep      rune
+	groupingSep     rune
+
+	// captureOriginals allows MaskWithOriginals, it must never be enabled in production
+	captureOriginals bool
+
+	underFields      []underFields
+	discriminators   []discriminatorRule
+	collapseArrays   map[string]struct{}
+	shuffleArrays    map[string]struct{}
+	truncateArrays   map[string]int
+	digitArrays      map[string]struct{}
+	hashSubtrees     map[string]struct{}
+	arrayFuncs       map[string]MaskArrayFunc
+	minDepths        map[string]int
+	shallowFields    map[string]struct{}
+	shuffleSeed      int64
+	maxArrayDepth    int
+	maxValueLength   int
+	longValuePolicy  LongValuePolicy
+	utf8Policy       UTF8Policy
+	markers          map[string]string
+	pathMarkers      map[string]string
+	redactedBlock    string
+	redactedFields   map[string]struct{}
+	redactedPaths    map[string]struct{}
+	digestHash       func() hash.Hash
+	floatFormatter   func(float64) string
+	pooled           bool
+	postValidate     func(masked []byte) error
+	onUnknownType    func(path, goType string)
+	redactValues     map[string]struct{}
+	expectedKeys     map[string]struct{}
+	pathTypes        map[string]string
+	mismatchPolicy   TypeMismatchPolicy
+	strictArrayTypes bool
+	modeGlobalFields map[string]map[string]struct{}
+
+	pathStringFuncs  map[string]MaskStringFunc
+	pathAssignFuncs  map[string]MaskAssignFunc
+	pathIntFuncs     map[string]MaskIntFunc
+	pathInt64Funcs   map[string]MaskInt64Func
+	pathFloat64Funcs map[string]MaskFloat64Func
+	pathValueFuncs   map[string]MaskValueFunc
+	objectFuncs      map[string]MaskObjectFunc
+	// maskedObjectFuncs are called with objects after their fields are masked
+	maskedObjectFuncs map[string]MaskObjectFunc
+}
+
+// state is a per-call masking state shared by the traversal methods
+type state struct {
+	mode string
+	// changed reports whether any value was masked, the input is returned as is otherwise
+	changed bool
+	// ancestors are maps and slices on the current path, tracked only for decoded input of MaskAny
+	ancestors map[uintptr]struct{}
+	// assign is Assigner of MaskAssignFunc funcs, it's created on the first use
+	assign *Assigner
+	// originals are original values of masked leaves by xpath, collected only by MaskWithOriginals
+	originals map[string]any
+	// arrays is the number of arrays on the current path
+	arrays int
+	// redacted are masked values moved out of the root object by WithRedactedBlock by xpath
+	redacted map[string]any
+	// depth is the number of objects and arrays holding the current value, top-level fields have depth 1
+	depth int
+	// patch are replace operations of masked leaves, collected only by MaskAsPatch
+	patch []PatchOp
+	// verbatim forces copying unmatched bytes as by WithCopyUnmatchedVerbatim, it's set by MaskStream
+	verbatim bool
+}
+
+// enterArray method for tracking array by xpath pk on the current path, returns ErrMaxArrayDepth
+// if arrays are nested deeper than WithMaxArrayDepth allows
+func (j *JsonMask) enterArray(st *state, pk string) error {
+	st.arrays++
+	if j.maxArrayDepth > 0 && st.arrays > j.maxArrayDepth {
+		return fmt.Errorf("%s: %w: limit %d", rootPath(pk), ErrMaxArrayDepth, j.maxArrayDepth)
+	}
+
+	return nil
+}
+
+// original method for recording the original value of the masked leaf by xpath fk
+func (st *state) original(fk string, val any) {
+	if st.originals != nil {
+		st.originals[fk] = val
+	}
+}
+
+// enter method for tracking map or slice on the current path, returns ErrCycle if it's already there
+func (st *state) enter(ptr uintptr, path string) error {
+	if _, ok := st.ancestors[ptr]; ok {
+		return fmt.Errorf("%s: %w", rootPath(path), ErrCycle)
+	}
+
+	st.ancestors[ptr] = struct{}{}
+	return nil
+}
+
+// leave method for removing map or slice from the current path
+func (st *state) leave(ptr uintptr) {
+	delete(st.ancestors, ptr)
+}
+
+// underFields is a list of field keys masked only beneath the xpath prefix
+type underFields struct {
+	prefix string
+	fields map[string]struct{}
 }
 
 // NewJSONMask initializes a JsonMask
 // Mask fields:
 // 1. Global (a,b,c) - will mask all encountered json fields (nested fields will be masked entirely)
-// 2. XPath (/a/b/c) - will mask only specified json fields by xpath
+// 2. XPath (/a/b/c, /a[0]/b, /a[1][0]) - will mask only specified json fields by xpath, nested arrays use one index per level
 func NewJSONMask(fields ...string) *JsonMask {
-	m := &JsonMask{
-		pathFields:   make(map[string]struct{}),
-		globalFields: make(map[string]struct{}),
-	}
+	return NewJSONMaskWithOptions(WithFields(fields...))
+}
 
+// addFields method for splitting fields on global and xpath ones
+func (j *JsonMask) addFields(fields ...string) {
 	for _, field := range fields {
 		if strings.Contains(field, pathKey) {
-			m.pathFields[field] = struct{}{}
+			j.pathFields[j.foldPath(field)] = struct{}{}
 		} else {
-			m.globalFields[field] = struct{}{}
+			j.globalFields[j.fold(field)] = struct{}{}
+		}
+	}
+}
+
+// fold method for normalizing keys and paths when matching is case-insensitive
+func (j *JsonMask) fold(s string) string {
+	if j.caseInsensitive {
+		return strings.ToLower(s)
+	}
+
+	return s
+}
+
+// foldPath method for normalizing configured xpath, the legacy root array form "[0]/key" is
+// rewritten to "/[0]/key" the traversal produces
+func (j *JsonMask) foldPath(path string) string {
+	if strings.HasPrefix(path, "[") {
+		path = pathKey + path
+	}
+
+	return j.fold(path)
+}
+
+// isPathField check path on contains in list at xpath fields
+func (j *JsonMask) isPathField(path string) bool {
+	_, ok := j.pathFields[j.pathKey(path)]
+	return ok
+}
+
+// pathKey method for normalizing the traversed path before lookup in configured xpath fields and funcs
+func (j *JsonMask) pathKey(path string) string {
+	if j.indexAgnostic {
+		path = stripIndices(path)
+	}
+
+	return j.fold(path)
+}
+
+// Validate method for checking configured xpath fields and xpaths of options and funcs registered by xpath
+// (WithPathType, WithCollapseArrays, WithHashSubtree, RegisterObjectFunc, ...), they must follow the grammar:
+// "/" for the root value or "/key" segments where every key is non-empty and may be followed by
+// array indices "[0]", items of a root array start with "/[0]" ("/[0]/key"). Key globs of WithKeyGlob and patterns
+// of RegisterRuleStringFunc must be valid path.Match patterns. All malformed paths and globs are reported
+func (j *JsonMask) Validate() error {
+	unique := make(map[string]struct{}, len(j.pathFields))
+	for path := range j.pathFields {
+		unique[path] = struct{}{}
+	}
+
+	for _, under := range j.underFields {
+		unique[under.prefix] = struct{}{}
+	}
+
+	addKeys(unique, j.pathTypes)
+	addKeys(unique, j.collapseArrays)
+	addKeys(unique, j.digitArrays)
+	addKeys(unique, j.hashSubtrees)
+	addKeys(unique, j.truncateArrays)
+	addKeys(unique, j.shuffleArrays)
+	addKeys(unique, j.redactedPaths)
+	addKeys(unique, j.objectFuncs)
+	addKeys(unique, j.maskedObjectFuncs)
+	addKeys(unique, j.arrayFuncs)
+
+	paths := make([]string, 0, len(unique))
+	for path := range unique {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		if err := validatePath(path); err != nil {
+			errs = append(errs, fmt.Errorf("%w %q: %v", ErrInvalidPath, path, err))
 		}
 	}
 
-	return m
+	for _, pattern := range j.keyGlobs {
+		if _, err := gopath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%w %q: %v", ErrInvalidPath, pattern, err))
+		}
+	}
+
+	for _, rule := range j.stringRules {
+		if _, err := gopath.Match(rule.pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%w %q: %v", ErrInvalidPath, rule.pattern, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetEnabled method for turning masking on and off at runtime, disabled JsonMask returns the input unchanged
+// after checking it's valid JSON. It's safe to call concurrently with masking
+func (j *JsonMask) SetEnabled(enabled bool) {
+	j.disabled.Store(!enabled)
 }
 
 // RegisterMaskStringFunc method for adding MaskStringFunc to JsonMask
 func (j *JsonMask) RegisterMaskStringFunc(fn MaskStringFunc) {
-	j.maskStringFunc = fn
+	j.maskStringFunc, j.maskAssignFunc = fn, nil
+}
+
+// RegisterMaskAssignFunc method for adding MaskAssignFunc to JsonMask instead of MaskStringFunc
+func (j *JsonMask) RegisterMaskAssignFunc(fn MaskAssignFunc) {
+	j.maskStringFunc, j.maskAssignFunc = nil, fn
+}
+
+// RegisterMaskKeyFunc method for adding MaskStringFunc masking keys of objects, it's called with the xpath
+// of the member and its key after the values are masked, so rules match the original keys. Masking fails
+// with ErrKeyCollision if a masked key equals another key of the object, ErrSkip keeps the key
+func (j *JsonMask) RegisterMaskKeyFunc(fn MaskStringFunc) {
+	j.maskKeyFunc = fn
+}
+
+// RegisterMaskRawStringFunc method for adding MaskRawStringFunc used for matched strings instead of other string funcs
+// by the token walking of WithCopyUnmatchedVerbatim and MaskStream, the decoded masking doesn't have raw tokens
+// and ignores it
+func (j *JsonMask) RegisterMaskRawStringFunc(fn MaskRawStringFunc) {
+	j.maskRawFunc = fn
+}
+
+// RegisterMaskNumberFunc method for adding MaskNumberFunc used for matched numbers instead of other number funcs,
+// numbers aren't converted into int or float so there are no precision concerns ("1e400" and "0.10" are passed
+// as is). The decoded masking of MaskAny doesn't have raw tokens and ignores it
+func (j *JsonMask) RegisterMaskNumberFunc(fn MaskNumberFunc) {
+	j.maskNumberFunc = fn
 }
 
 // RegisterMaskIntFunc method for adding MaskIntFunc to JsonMask
@@ -69,257 +395,1686 @@ func (j *JsonMask) RegisterMaskIntFunc(fn MaskIntFunc) {
 	j.maskIntFunc = fn
 }
 
+// RegisterMaskInt64Func method for adding MaskInt64Func to JsonMask, it takes precedence over MaskIntFunc
+func (j *JsonMask) RegisterMaskInt64Func(fn MaskInt64Func) {
+	j.maskInt64Func = fn
+}
+
 // RegisterMaskFloat64Func method for adding MaskFloat64Func to JsonMask
 func (j *JsonMask) RegisterMaskFloat64Func(fn MaskFloat64Func) {
 	j.maskFloat64Func = fn
 }
 
-// Mask method for masking JSON fields globally or by xpath
-func (j *JsonMask) Mask(value string) (string, error) {
-	var m map[string]any
-	if err := json.Unmarshal([]byte(value), &m); err != nil {
-		return "", fmt.Errorf("json unmarshal: %w", err)
+// RegisterMaskValueFunc method for adding MaskValueFunc to JsonMask, it receives leaf values of any type
+// (string, float64, bool, nil) and takes precedence over typed funcs
+func (j *JsonMask) RegisterMaskValueFunc(fn MaskValueFunc) {
+	j.maskValueFunc = fn
+}
+
+// MaskFuncType is a value type with a typed mask func, it's used by RegisterMaskFunc
+type MaskFuncType interface {
+	string | int | int64 | float64
+}
+
+// RegisterMaskFunc registers fn as the typed mask func of JsonMask by its value type, it's the same as calling
+// RegisterMaskStringFunc, RegisterMaskIntFunc, RegisterMaskInt64Func or RegisterMaskFloat64Func.
+// Booleans have no typed func, they are masked by MaskValueFunc
+func RegisterMaskFunc[T MaskFuncType](j *JsonMask, fn func(path string, value T) (T, error)) {
+	switch f := any(fn).(type) {
+	case func(string, string) (string, error):
+		j.RegisterMaskStringFunc(f)
+	case func(string, int) (int, error):
+		j.RegisterMaskIntFunc(f)
+	case func(string, int64) (int64, error):
+		j.RegisterMaskInt64Func(f)
+	case func(string, float64) (float64, error):
+		j.RegisterMaskFloat64Func(f)
 	}
+}
 
-	if err := j.mask("", m, true); err != nil {
-		return "", fmt.Errorf("mask: %w", err)
+// RegisterDefaultStringFunc method for adding MaskStringFunc applied to string values not matched by any rule,
+// matched values are still masked by the func registered by RegisterMaskStringFunc
+func (j *JsonMask) RegisterDefaultStringFunc(fn MaskStringFunc) {
+	j.defaultStringFunc = fn
+}
+
+// RegisterPathMaskStringFunc method for adding MaskStringFunc to JsonMask for xpath only,
+// the path is masked with fn instead of the func registered by RegisterMaskStringFunc
+func (j *JsonMask) RegisterPathMaskStringFunc(path string, fn MaskStringFunc) {
+	j.pathFields[j.foldPath(path)] = struct{}{}
+	j.pathStringFuncs[j.foldPath(path)] = fn
+	delete(j.pathAssignFuncs, j.foldPath(path))
+}
+
+// RegisterPathMaskAssignFunc method for adding MaskAssignFunc to JsonMask for xpath only,
+// the path is masked with fn instead of the common func
+func (j *JsonMask) RegisterPathMaskAssignFunc(path string, fn MaskAssignFunc) {
+	j.pathFields[j.foldPath(path)] = struct{}{}
+	j.pathAssignFuncs[j.foldPath(path)] = fn
+	delete(j.pathStringFuncs, j.foldPath(path))
+}
+
+// RegisterRuleStringFunc method for adding MaskStringFunc for fields matching the path.Match pattern with
+// the priority, patterns with "/" are matched against the xpath without array indices ("/users/*/email")
+// and others against the key ("*_token"). The func of a string is resolved in the order:
+//
+//	rules with priority above 0, higher first and equal ones in the registration order
+//	funcs of RegisterPathMaskStringFunc
+//	rules with priority 0 or below in the same order
+//	the common func of RegisterMaskStringFunc
+func (j *JsonMask) RegisterRuleStringFunc(pattern string, priority int, fn MaskStringFunc) {
+	rule := stringRule{pattern: j.fold(pattern), path: strings.Contains(pattern, pathKey), priority: priority, fn: fn}
+	if rule.path {
+		rule.pattern = j.foldPath(pattern)
 	}
 
-	b, err := json.Marshal(m)
+	j.stringRules = append(j.stringRules, rule)
+	sort.SliceStable(j.stringRules, func(a, b int) bool { return j.stringRules[a].priority > j.stringRules[b].priority })
+}
+
+// RegisterPathMaskIntFunc method for adding MaskIntFunc to JsonMask for xpath only
+func (j *JsonMask) RegisterPathMaskIntFunc(path string, fn MaskIntFunc) {
+	j.pathFields[j.foldPath(path)] = struct{}{}
+	j.pathIntFuncs[j.foldPath(path)] = fn
+}
+
+// RegisterPathMaskInt64Func method for adding MaskInt64Func to JsonMask for xpath only
+func (j *JsonMask) RegisterPathMaskInt64Func(path string, fn MaskInt64Func) {
+	j.pathFields[j.foldPath(path)] = struct{}{}
+	j.pathInt64Funcs[j.foldPath(path)] = fn
+}
+
+// RegisterPathMaskValueFunc method for adding MaskValueFunc to JsonMask for xpath only
+func (j *JsonMask) RegisterPathMaskValueFunc(path string, fn MaskValueFunc) {
+	j.pathFields[j.foldPath(path)] = struct{}{}
+	j.pathValueFuncs[j.foldPath(path)] = fn
+}
+
+// RegisterPathValueRegex method for replacing matches of re inside string values by xpath with the replacement
+// (regexp.ReplaceAllString syntax, "$1" expands groups), values of other types and strings without matches
+// are left unchanged
+func (j *JsonMask) RegisterPathValueRegex(path string, re *regexp.Regexp, replacement string) {
+	j.RegisterPathMaskValueFunc(path, func(_ string, val any) (any, error) {
+		s, ok := val.(string)
+		if !ok || !re.MatchString(s) {
+			return val, ErrSkip
+		}
+
+		return re.ReplaceAllString(s, replacement), nil
+	})
+}
+
+// RegisterObjectFunc method for adding MaskObjectFunc called with the object by the xpath ("/" for the root object)
+// before its fields are masked, so keys added by the func are masked by the rules too
+func (j *JsonMask) RegisterObjectFunc(path string, fn MaskObjectFunc) {
+	j.objectFuncs[j.foldPath(path)] = fn
+}
+
+// RegisterMaskedObjectFunc method for adding MaskObjectFunc called with the object by the xpath ("/" for the root
+// object) after its fields are masked, so the func sees masked values and keys set by it aren't masked again
+func (j *JsonMask) RegisterMaskedObjectFunc(path string, fn MaskObjectFunc) {
+	j.maskedObjectFuncs[j.foldPath(path)] = fn
+}
+
+// RegisterArrayFunc method for adding MaskArrayFunc called with items of the array by the xpath ("/" for the root
+// array) instead of masking them one by one, e.g. replacing numbers with their mean. ErrSkip masks the items as usual
+func (j *JsonMask) RegisterArrayFunc(path string, fn MaskArrayFunc) {
+	j.arrayFuncs[j.foldPath(path)] = fn
+}
+
+// RegisterPathMaskFloat64Func method for adding MaskFloat64Func to JsonMask for xpath only
+func (j *JsonMask) RegisterPathMaskFloat64Func(path string, fn MaskFloat64Func) {
+	j.pathFields[j.foldPath(path)] = struct{}{}
+	j.pathFloat64Funcs[j.foldPath(path)] = fn
+}
+
+// Mask method for masking JSON fields globally or by xpath,
+// the value is returned as is when no field was masked
+func (j *JsonMask) Mask(value string) (string, error) {
+	return j.maskJSONString(&state{}, value)
+}
+
+// MaskBytes method for masking JSON fields globally or by xpath,
+// the same value slice is returned when no field was masked
+func (j *JsonMask) MaskBytes(value []byte) ([]byte, error) {
+	return j.maskJSON(&state{}, value)
+}
+
+// maskJSONString method for masking JSON string with the per-call state
+func (j *JsonMask) maskJSONString(st *state, value string) (string, error) {
+	b, err := j.maskJSON(st, []byte(value))
 	if err != nil {
-		return "", fmt.Errorf("json marshal: %w", err)
+		return "", err
+	}
+
+	if !st.changed {
+		return value, nil
 	}
 
 	return string(b), nil
 }
 
-// mask method for masking parsed map with global and xpath fields
-func (j *JsonMask) mask(pk string, m map[string]any, ignoreGlobal bool) (err error) {
-	for k, val := range m {
-		fk := pk + pathKey + k
-		switch v := val.(type) {
-		case map[string]any:
-			ignoreGlobalVal := !(!ignoreGlobal || j.isGlobalField(k))
-			if err = j.mask(fk, v, ignoreGlobalVal); err != nil {
-				return err
-			}
-		case []any:
-			if err = j.maskSlice(k, fk, v, ignoreGlobal); err != nil {
-				return err
-			}
-		case string:
-			if j.maskStringFunc != nil {
-				if !ignoreGlobal || j.isGlobalField(k) {
-					m[k], err = j.maskStringFunc(fk, v)
-					if err != nil {
-						return err
-					}
-				}
-
-				if _, ok := j.pathFields[fk]; ok {
-					m[k], err = j.maskStringFunc(fk, v)
-					if err != nil {
-						return err
-					}
-				}
-			}
-		case float64:
-			if isInteger(v) {
-				if j.maskIntFunc == nil {
-					break
-				}
+// maskJSON method for masking JSON value with the per-call state and checking the output by WithPostValidate
+func (j *JsonMask) maskJSON(st *state, value []byte) ([]byte, error) {
+	if j.disabled.Load() {
+		return j.maskDocument(st, value)
+	}
 
-				if !ignoreGlobal || j.isGlobalField(k) {
-					m[k], err = j.maskIntFunc(fk, int(v))
-					if err != nil {
-						return err
-					}
-				}
+	b, err := j.maskDocument(st, value)
+	if err != nil {
+		return nil, err
+	}
 
-				if _, ok := j.pathFields[fk]; ok {
-					m[k], err = j.maskIntFunc(fk, int(v))
-					if err != nil {
-						return err
-					}
-				}
-			}
+	if err := j.validateOutput(b); err != nil {
+		return nil, err
+	}
 
-			if j.maskFloat64Func == nil {
-				break
-			}
+	return b, nil
+}
 
-			if !ignoreGlobal || j.isGlobalField(k) {
-				m[k], err = j.maskFloat64Func(fk, v)
-				if err != nil {
-					return err
-				}
-			}
+// validateOutput method for checking the masked output by the hook of WithPostValidate
+func (j *JsonMask) validateOutput(b []byte) error {
+	if j.postValidate == nil {
+		return nil
+	}
 
-			if _, ok := j.pathFields[fk]; ok {
-				m[k], err = j.maskFloat64Func(fk, v)
-				if err != nil {
-					return err
-				}
-			}
-		case bool, nil: // skip nil or boolean types
-		default:
-			return fmt.Errorf("unknow type: %T", v)
-		}
+	if err := j.postValidate(b); err != nil {
+		return fmt.Errorf("%w: %w", ErrPostValidate, err)
 	}
 
 	return nil
 }
 
-// maskSlice method for masking values what inside array
-func (j *JsonMask) maskSlice(k, pk string, sl []any, ignoreGlobal bool) (err error) {
-	for i, val := range sl {
-		fk := fmt.Sprintf("%s[%d]", pk, i)
-		switch v := val.(type) {
-		case map[string]any:
-			ignoreGlobalVal := !(!ignoreGlobal || j.isGlobalField(k))
-			if err = j.mask(fk, v, ignoreGlobalVal); err != nil {
-				return err
-			}
-		case []any:
-			if err = j.maskSlice(k, fk, v, ignoreGlobal); err != nil {
-				return err
-			}
-		case string:
-			if j.maskStringFunc != nil {
-				if !ignoreGlobal || j.isGlobalField(k) {
-					sl[i], err = j.maskStringFunc(fk, v)
-					if err != nil {
-						return err
-					}
-				}
+// maskDocument method for unmarshaling, masking and marshaling JSON value with the per-call state
+func (j *JsonMask) maskDocument(st *state, value []byte) ([]byte, error) {
+	if j.disabled.Load() {
+		if !json.Valid(value) {
+			_, err := j.unmarshal(value)
+			return nil, fmt.Errorf("json unmarshal: %w", err)
+		}
 
-				if _, ok := j.pathFields[fk]; ok {
-					sl[i], err = j.maskStringFunc(fk, v)
-					if err != nil {
-						return err
-					}
-				}
-			}
-		case float64:
-			if isInteger(v) {
-				if j.maskIntFunc == nil {
-					break
-				}
+		return value, nil
+	}
 
-				if !ignoreGlobal || j.isGlobalField(k) {
-					sl[i], err = j.maskIntFunc(fk, int(v))
-					if err != nil {
-						return err
-					}
-				}
+	if err := j.checkUTF8(value); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
 
-				if _, ok := j.pathFields[fk]; ok {
-					sl[i], err = j.maskIntFunc(fk, int(v))
-					if err != nil {
-						return err
-					}
-				}
-			}
+	if (j.verbatim || st.verbatim) && j.redactedBlock == "" {
+		return j.maskVerbatim(st, value)
+	}
 
-			if j.maskFloat64Func == nil {
-				break
-			}
+	var (
+		v   any
+		pv  *pooledValues
+		err error
+	)
+	// originals returned by MaskWithOriginals could reference decoded values, they aren't pooled
+	if j.pooled && st.originals == nil {
+		v, pv, err = j.unmarshalPooled(value)
+		defer pv.release()
+	} else {
+		v, err = j.unmarshal(value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
 
-			if !ignoreGlobal || j.isGlobalField(k) {
-				if sl[i], err = j.maskFloat64Func(fk, v); err != nil {
-					return err
-				}
-			}
+	v, err = j.maskRoot(st, v)
+	if err != nil {
+		return nil, fmt.Errorf("mask: %w", err)
+	}
 
-			if _, ok := j.pathFields[fk]; ok {
-				sl[i], err = j.maskFloat64Func(fk, v)
-				if err != nil {
-					return err
-				}
-			}
-		case bool, nil: // skip nil or boolean types
-		default:
-			return fmt.Errorf("unknow type: %T", v)
-		}
+	if !st.changed {
+		return value, nil
 	}
 
-	return nil
-}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json marshal: %w", err)
+	}
 
-// isGlobalFields check field on contains in list at global fields
-func (j *JsonMask) isGlobalField(field string) bool {
-	_, ok := j.globalFields[field]
-	return ok
+	return b, nil
 }
 
-// MaskFilledString masks the string length of the value with the same length or by passed length
-func MaskFilledString(maskChar string, length ...int) MaskStringFunc {
-	hasLen := len(length) > 0
-	return func(_, val string) (string, error) {
-		if hasLen {
-			return strings.Repeat(maskChar, length[0]), nil
+// unmarshal method for decoding JSON value into any, objects are decoded into orderedObject by WithOrderedKeys,
+// numbers are decoded into json.Number to re-emit unmasked numbers with the original representation.
+// Valid documents are decoded by decodeValid, json.Decoder reports errors of invalid ones
+func (j *JsonMask) unmarshal(value []byte) (any, error) {
+	if j.orderedKeys {
+		return decodeOrdered(value)
+	}
+
+	if json.Valid(value) {
+		return decodeValid(string(value), nil), nil
+	}
+
+	dec := newDecoder(value)
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
 		}
 
-		return strings.Repeat(maskChar, utf8.RuneCountInString(val)), nil
+		return nil, err
 	}
+
+	if err := checkEOF(dec); err != nil {
+		return nil, err
+	}
+
+	return v, nil
 }
 
-// MaskHashString masks and hashes (sha1) a string
-func MaskHashString() MaskStringFunc {
-	return func(_, val string) (string, error) {
-		hash := sha1.Sum(([]byte)(val))
-		return hex.EncodeToString(hash[:]), nil
+// MaskAny method for masking already decoded JSON value (map[string]any, []any, string, float64, bool, nil),
+// maps and slices are masked in place, the returned value must be used for a primitive root value.
+// ErrCycle is returned if a map or slice contains itself
+func (j *JsonMask) MaskAny(value any) (any, error) {
+	if j.disabled.Load() {
+		return value, nil
 	}
+
+	v, err := j.maskRoot(&state{ancestors: make(map[uintptr]struct{})}, value)
+	if err != nil {
+		return nil, fmt.Errorf("mask: %w", err)
+	}
+
+	return v, nil
 }
 
-// MaskRandomInt masks converts an integer (int) into a random number in range (default 1000)
-func MaskRandomInt(arg ...int) MaskIntFunc {
-	hasArg := len(arg) > 0
-	return func(_ string, val int) (int, error) {
-		rn := randomIntRange
-		if hasArg {
-			rn = arg[0]
-		}
+// MaskAnyCopy method for masking already decoded JSON value without mutating it, maps and slices are
+// deep-copied before masking, so it allocates the whole tree again unlike MaskAny
+func (j *JsonMask) MaskAnyCopy(value any) (any, error) {
+	v, err := deepCopy(value, "", make(map[uintptr]struct{}))
+	if err != nil {
+		return nil, fmt.Errorf("copy: %w", err)
+	}
 
-		return rand.Intn(rn), nil
+	return j.MaskAny(v)
+}
+
+// MaskStruct method for masking Go value src by marshaling it to JSON and unmarshaling the masked JSON into dst,
+// field names of global and xpath fields follow JSON tags of the struct fields.
+// Masked values must fit the types of dst fields, e.g. a hash can't be unmarshaled into an int field
+func (j *JsonMask) MaskStruct(src, dst any) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+
+	masked, err := j.MaskBytes(b)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(masked, dst); err != nil {
+		return fmt.Errorf("json unmarshal: %w", err)
 	}
+
+	return nil
 }
 
-// MaskRandomFloat64 converts a float64 to a random number in range (default 1000.3)
-// if you pass "1000.3" to arg, it sets a random number in the range of 0.000 to 999.999
-func MaskRandomFloat64(arg ...string) MaskFloat64Func {
-	hasArg := len(arg) > 0
-	return func(_ string, val float64) (float64, error) {
-		var (
-			i, d int
-			err  error
-			rn   = randomFloatRange
-		)
+// maskRoot method for masking parsed root value, a primitive root value is matched by xpath "/"
+func (j *JsonMask) maskRoot(st *state, val any) (any, error) {
+	switch v := val.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
 
-		if hasArg {
-			rn = arg[0]
+		if err := j.checkTopLevelKeys(keys); err != nil {
+			return nil, err
 		}
 
-		digits := strings.Split(rn, ".")
-		if len(digits) > 0 {
-			if i, err = strconv.Atoi(digits[0]); err != nil {
-				return 0, err
-			}
+		if err := j.maskObjectFunc(st, j.objectFuncs, "", v); err != nil {
+			return nil, err
 		}
-		if len(digits) == 2 {
-			if d, err = strconv.Atoi(digits[1]); err != nil {
-				return 0, err
-			}
+
+		st.redacted = j.newRedacted()
+		if _, err := j.walk(st, "", "", v, true); err != nil {
+			return nil, err
 		}
 
-		dd := math.Pow10(d)
-		x := float64(int(rand.Float64() * float64(i) * dd))
+		return j.addRedacted(st, v)
+	case orderedObject:
+		keys := make([]string, 0, len(v))
+		for _, m := range v {
+			keys = append(keys, m.key)
+		}
 
-		return x / dd, nil
+		if err := j.checkTopLevelKeys(keys); err != nil {
+			return nil, err
+		}
+
+		v, err := j.maskOrderedObjectFunc(st, j.objectFuncs, "", v)
+		if err != nil {
+			return nil, err
+		}
+
+		st.redacted = j.newRedacted()
+		res, err := j.walk(st, "", "", v, true)
+		if err != nil {
+			return nil, err
+		}
+
+		return j.addRedacted(st, res)
+	case []any:
+		if res, ok, err := j.maskArrayFunc(st, pathKey, v); ok || err != nil {
+			return res, err
+		}
+
+		return j.walk(st, "", pathKey, v, true)
+	default:
+		// wrapping into the map with an empty key gives the primitive value path "/"
+		root := map[string]any{"": v}
+		fr, err := j.newFrame(st, "", "", root, true)
+		if err != nil {
+			return nil, err
+		}
+
+		fr.wrapper = true
+		if err := j.traverse(st, fr); err != nil {
+			return nil, err
+		}
+
+		return root[""], nil
+	}
+}
+
+// maskArrayFunc method for calling MaskArrayFunc registered for the array by xpath fk,
+// returns false if there is no func or it skips the array
+func (j *JsonMask) maskArrayFunc(st *state, fk string, sl []any) (any, bool, error) {
+	fn, ok := j.arrayFuncs[j.pathKey(fk)]
+	if !ok {
+		return sl, false, nil
+	}
+
+	res, err := fn(fk, sl)
+	if errors.Is(err, ErrSkip) {
+		return sl, false, nil
+	}
+
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %w", fk, err)
 	}
+
+	st.changed = true
+	return res, true, nil
+}
+
+// maskObjectFunc method for calling MaskObjectFunc of funcs registered for the object by xpath fk
+func (j *JsonMask) maskObjectFunc(st *state, funcs map[string]MaskObjectFunc, fk string, m map[string]any) error {
+	fn, ok := funcs[j.pathKey(rootPath(fk))]
+	if !ok {
+		return nil
+	}
+
+	if err := fn(m); err != nil {
+		return fmt.Errorf("%s: %w", rootPath(fk), err)
+	}
+
+	st.changed = true
+	return nil
+}
+
+// checkTopLevelKeys method for checking keys of the root object on WithExpectedTopLevelKeys,
+// returns ErrUnexpectedKey listing all unexpected keys
+func (j *JsonMask) checkTopLevelKeys(keys []string) error {
+	if j.expectedKeys == nil {
+		return nil
+	}
+
+	var unexpected []string
+	for _, k := range keys {
+		if _, ok := j.expectedKeys[j.fold(k)]; !ok {
+			unexpected = append(unexpected, strconv.Quote(k))
+		}
+	}
+
+	if len(unexpected) == 0 {
+		return nil
+	}
+
+	sort.Strings(unexpected)
+	return fmt.Errorf("%w: %s", ErrUnexpectedKey, strings.Join(unexpected, ", "))
+}
+
+// maskValue method for masking value of field k by xpath fk, returns the masked value
+func (j *JsonMask) maskValue(st *state, k, fk string, val any, ignoreGlobal bool) (any, error) {
+	res, fr, err := j.maskNode(st, k, fk, val, ignoreGlobal)
+	if err != nil || fr == nil {
+		return res, err
+	}
+
+	if err := j.traverse(st, fr); err != nil {
+		return nil, err
+	}
+
+	return fr.value(), nil
+}
+
+// maskNode method for masking value of field k by xpath fk without descending into it,
+// returns the masked value and the frame of its items for objects and arrays
+func (j *JsonMask) maskNode(st *state, k, fk string, val any, ignoreGlobal bool) (any, *frame, error) {
+	if len(j.pathTypes) > 0 {
+		v, skip, err := j.checkPathType(st, fk, val)
+		if err != nil || skip {
+			return v, nil, err
+		}
+		val = v
+	}
+
+	if _, ok := j.hashSubtrees[j.pathKey(fk)]; ok {
+		if res, ok, err := j.hashSubtree(st, val); ok || err != nil {
+			return res, nil, err
+		}
+	}
+
+	if apply, kind := j.match(fk, val); apply && kind == MatchAuto {
+		ignoreGlobal = false
+	} else if apply {
+		res, err := j.maskKind(st, k, fk, val, kind)
+		return res, nil, err
+	}
+
+	switch v := val.(type) {
+	case map[string]any:
+		if err := j.maskObjectFunc(st, j.objectFuncs, fk, v); err != nil {
+			return nil, nil, err
+		}
+
+		ignoreGlobalVal := !(!ignoreGlobal || j.isGlobalField(st, k, fk))
+		fr, err := j.initFrame(j.newPooledFrame(), st, "", fk, v, ignoreGlobalVal)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fr.shallow = j.isShallow(k, ignoreGlobal, ignoreGlobalVal)
+		return v, fr, nil
+	case orderedObject:
+		v, err := j.maskOrderedObjectFunc(st, j.objectFuncs, fk, v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ignoreGlobalVal := !(!ignoreGlobal || j.isGlobalField(st, k, fk))
+		fr, err := j.initFrame(j.newPooledFrame(), st, "", fk, v, ignoreGlobalVal)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fr.shallow = j.isShallow(k, ignoreGlobal, ignoreGlobalVal)
+		return v, fr, nil
+	case []any:
+		if res, ok, err := j.maskArrayFunc(st, fk, v); ok || err != nil {
+			return res, nil, err
+		}
+
+		if _, ok := j.collapseArrays[j.pathKey(fk)]; ok {
+			st.changed = true
+			return len(v), nil, nil
+		}
+
+		if _, ok := j.digitArrays[j.pathKey(fk)]; ok {
+			if res, ok, err := j.maskDigitArray(st, fk, v); ok || err != nil {
+				return res, nil, err
+			}
+		}
+
+		fr, err := j.initFrame(j.newPooledFrame(), st, k, fk, v, ignoreGlobal)
+		return v, fr, err
+	case json.Number:
+		if j.maskNumberFunc != nil {
+			res, err := j.maskRawNumber(st, k, fk, v, ignoreGlobal)
+			return res, nil, err
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			res, err := j.maskOutOfRange(st, k, fk, v, ignoreGlobal)
+			return res, nil, err
+		}
+
+		res, err := j.maskNumberLeaf(st, k, fk, f, v, ignoreGlobal)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// unmasked number keeps the original representation
+		if r, ok := res.(float64); ok && r == f {
+			return v, nil, nil
+		}
+
+		return res, nil, nil
+	case string:
+		if fn := j.valueFunc(fk); fn != nil && (j.isStringMatched(st, k, fk, ignoreGlobal) || j.isRedactValue(v)) {
+			res, err := j.maskLeaf(st, fk, v, fn)
+			return res, nil, err
+		}
+		res, err := j.maskString(st, k, fk, v, ignoreGlobal)
+		return res, nil, err
+	case float64:
+		res, err := j.maskNumberLeaf(st, k, fk, v, "", ignoreGlobal)
+		return res, nil, err
+	case bool: // skip boolean types without MaskValueFunc
+		if fn := j.valueFunc(fk); fn != nil && j.isMatched(st, k, fk, ignoreGlobal) {
+			res, err := j.maskLeaf(st, fk, v, fn)
+			return res, nil, err
+		}
+		return v, nil, nil
+	case nil:
+		if fn := j.valueFunc(fk); fn != nil && j.isMatched(st, k, fk, ignoreGlobal) {
+			res, err := j.maskLeaf(st, fk, v, fn)
+			return res, nil, err
+		}
+		return j.maskNull(st, k, fk, ignoreGlobal), nil, nil
+	default:
+		if j.onUnknownType != nil {
+			j.onUnknownType(fk, fmt.Sprintf("%T", v))
+			return v, nil, nil
+		}
+		return nil, nil, fmt.Errorf("unknow type: %T", v)
+	}
+}
+
+// maskRawNumber method for masking the raw number token with MaskNumberFunc, the result must be a JSON number
+func (j *JsonMask) maskRawNumber(st *state, k, fk string, v json.Number, ignoreGlobal bool) (any, error) {
+	if !j.isMatched(st, k, fk, ignoreGlobal) {
+		return v, nil
+	}
+
+	res, err := j.maskNumberFunc(fk, string(v))
+	if errors.Is(err, ErrSkip) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNumberToken(res) {
+		return nil, fmt.Errorf("%s: %w: %q", fk, ErrInvalidNumberToken, res)
+	}
+
+	st.changed = true
+	return json.Number(res), nil
+}
+
+// isNumberToken check the token on being a single JSON number
+func isNumberToken(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+		return false
+	}
+
+	return json.Valid([]byte(s))
+}
+
+// maskOutOfRange method for the number out of float64 range (1e400) which can't be masked by the number funcs,
+// the matched number fails with ErrNumberRange if it has a func so the sensitive value isn't let through
+func (j *JsonMask) maskOutOfRange(st *state, k, fk string, v json.Number, ignoreGlobal bool) (any, error) {
+	if !j.isMatched(st, k, fk, ignoreGlobal) || (!j.hasNumberFunc(fk) && j.valueFunc(fk) == nil) {
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("%s: %w: %s", fk, ErrNumberRange, v)
+}
+
+// maskNumberLeaf method for masking number value with MaskValueFunc or the number funcs, num is the decoded
+// json.Number of the value or empty
+func (j *JsonMask) maskNumberLeaf(st *state, k, fk string, v float64, num json.Number, ignoreGlobal bool) (any, error) {
+	if fn := j.valueFunc(fk); fn != nil && j.isMatched(st, k, fk, ignoreGlobal) {
+		return j.maskLeaf(st, fk, v, fn)
+	}
+
+	return j.maskNumber(st, k, fk, v, num, ignoreGlobal)
+}
+
+// maskLeaf method for masking leaf value of any type with MaskValueFunc
+func (j *JsonMask) maskLeaf(st *state, fk string, v any, fn MaskValueFunc) (any, error) {
+	res, err := fn(fk, v)
+	if errors.Is(err, ErrSkip) {
+		return v, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	st.changed = true
+	return res, nil
+}
+
+// maskString method for masking string value with MaskStringFunc
+func (j *JsonMask) maskString(st *state, k, fk, v string, ignoreGlobal bool) (any, error) {
+	fn := j.stringFunc(st, k, fk)
+	switch {
+	case j.isRedactValue(v):
+		// redact values are masked by the string func regardless of the field
+	case !j.isStringMatched(st, k, fk, ignoreGlobal):
+		fn = j.defaultStringFunc
+	case j.numericStrings && j.hasNumberFunc(fk) && j.isMatched(st, k, fk, ignoreGlobal):
+		if num, ok := j.parseNumericString(v); ok {
+			return j.maskNumericString(st, k, fk, v, num, ignoreGlobal)
+		}
+	}
+
+	if fn == nil {
+		return v, nil
+	}
+
+	val, err := j.validUTF8(fk, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if val, err = j.limitLength(fk, val); err != nil {
+		return nil, err
+	}
+
+	res, err := fn(fk, val)
+	if errors.Is(err, ErrSkip) {
+		return v, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	st.changed = true
+	return res, nil
+}
+
+// maskNull method for masking null value with the placeholder of WithMaskNull, null is skipped by default
+func (j *JsonMask) maskNull(st *state, k, fk string, ignoreGlobal bool) any {
+	if !j.isMatched(st, k, fk, ignoreGlobal) {
+		return nil
+	}
+
+	// matched null is reported even if it's kept to tell the present field from the absent one
+	st.original(fk, nil)
+	if !j.maskNulls {
+		return nil
+	}
+
+	st.changed = true
+	return j.nullPlaceholder
+}
+
+// maskNumber method for masking number value with MaskInt64Func/MaskIntFunc for integers and MaskFloat64Func,
+// num is the decoded json.Number of the value parsed into int64 exactly, it's empty for float64 values.
+// Whole numbers out of range of the integer func are masked by MaskFloat64Func or fail with ErrNumberRange
+func (j *JsonMask) maskNumber(st *state, k, fk string, v float64, num json.Number, ignoreGlobal bool) (any, error) {
+	if !j.isMatched(st, k, fk, ignoreGlobal) {
+		return v, nil
+	}
+
+	if v == math.Trunc(v) {
+		var (
+			r   any
+			err error
+		)
+
+		n, ok := toInt64(v, num)
+		int64Fn, intFn := j.integerFuncs(fk)
+		switch {
+		case int64Fn == nil && intFn == nil: // integers are masked by MaskFloat64Func without integer funcs
+			return j.maskNumberFloat64(st, fk, v)
+		case int64Fn != nil && ok:
+			r, err = int64Fn(fk, n)
+		case intFn != nil && ok && n >= math.MinInt && n <= math.MaxInt:
+			r, err = intFn(fk, int(n))
+		case j.float64Func(fk) != nil:
+			return j.maskNumberFloat64(st, fk, v)
+		default:
+			return nil, fmt.Errorf("%s: %w: %v", fk, ErrNumberRange, numberString(v, num))
+		}
+
+		if errors.Is(err, ErrSkip) {
+			return v, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		st.changed = true
+		return r, nil
+	}
+
+	return j.maskNumberFloat64(st, fk, v)
+}
+
+// toInt64 converts the whole number v into int64, json.Number num is parsed exactly since float64 can't hold
+// all of int64 values (9007199254740993), returns false if the number doesn't fit into int64
+func toInt64(v float64, num json.Number) (int64, bool) {
+	if num != "" {
+		if n, err := num.Int64(); err == nil {
+			return n, true
+		}
+	}
+
+	if isInteger(v) {
+		return int64(v), true
+	}
+
+	return 0, false
+}
+
+// numberString returns the decimal form of the number, json.Number keeps the original representation
+func numberString(v float64, num json.Number) string {
+	if num != "" {
+		return num.String()
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// maskNumberFloat64 method for masking number value with MaskFloat64Func, the result is formatted by
+// WithFloatFormatter or whole result of the integer value is kept integer to not be re-emitted as float
+func (j *JsonMask) maskNumberFloat64(st *state, fk string, v float64) (any, error) {
+	floatFn := j.float64Func(fk)
+	if floatFn == nil {
+		return v, nil
+	}
+
+	r, err := j.maskFloat64(floatFn, fk, v)
+	if errors.Is(err, ErrSkip) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st.changed = true
+
+	if j.floatFormatter != nil {
+		return json.Number(j.floatFormatter(r)), nil
+	}
+
+	if isInteger(v) && isInteger(r) {
+		return int64(r), nil
+	}
+
+	return r, nil
+}
+
+// maskFloat64 method for calling MaskFloat64Func with check of the result on NaN/Inf
+func (j *JsonMask) maskFloat64(fn MaskFloat64Func, path string, val float64) (float64, error) {
+	res, err := fn(path, val)
+	if err != nil {
+		return 0, err
+	}
+
+	if !math.IsNaN(res) && !math.IsInf(res, 0) {
+		return res, nil
+	}
+
+	if !j.clampNonFinite {
+		return 0, fmt.Errorf("%s: %w: %v", path, ErrNonFiniteFloat, res)
+	}
+
+	switch {
+	case math.IsInf(res, 1):
+		return math.MaxFloat64, nil
+	case math.IsInf(res, -1):
+		return -math.MaxFloat64, nil
+	default:
+		return 0, nil
+	}
+}
+
+// isMatched check value of field k by xpath fk on matching any of global or xpath rules,
+// the value is masked once even if several rules match it
+func (j *JsonMask) isMatched(st *state, k, fk string, ignoreGlobal bool) bool {
+	return !ignoreGlobal || j.isGlobalField(st, k, fk) || j.isPathField(fk)
+}
+
+// isStringMatched check string value of field k by xpath fk on matching any of global or xpath rules
+// or patterns of RegisterRuleStringFunc, the patterns don't match values of other types
+func (j *JsonMask) isStringMatched(st *state, k, fk string, ignoreGlobal bool) bool {
+	if j.isMatched(st, k, fk, ignoreGlobal) {
+		return true
+	}
+
+	_, ok := j.stringRule(k, fk)
+	return ok
+}
+
+// isRedactValue check string value on contains in list at redact values
+func (j *JsonMask) isRedactValue(val string) bool {
+	_, ok := j.redactValues[val]
+	return ok
+}
+
+// isGlobalFields check field on contains in list at global fields or matching prefix/suffix fields,
+// path is the xpath of the field value used by fields scoped under a prefix
+func (j *JsonMask) isGlobalField(st *state, field, path string) bool {
+	field = j.fold(field)
+	if _, ok := j.globalFields[field]; ok && st.depth >= j.minDepths[field] {
+		return true
+	}
+
+	if st.mode != "" {
+		if _, ok := j.modeGlobalFields[st.mode][field]; ok {
+			return true
+		}
+	}
+
+	for _, prefix := range j.prefixFields {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+
+	for _, suffix := range j.suffixFields {
+		if strings.HasSuffix(field, suffix) {
+			return true
+		}
+	}
+
+	for _, pattern := range j.keyGlobs {
+		if ok, _ := gopath.Match(pattern, field); ok {
+			return true
+		}
+	}
+
+	if len(j.underFields) > 0 {
+		path = j.fold(path)
+		for _, under := range j.underFields {
+			if _, ok := under.fields[field]; ok && isUnder(path, under.prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// stringFunc returns MaskStringFunc of field k registered for the path, by RegisterRuleStringFunc or the common one,
+// MaskAssignFunc is bound to Assigner of the per-call state st
+func (j *JsonMask) stringFunc(st *state, k, path string) MaskStringFunc {
+	rule, hasRule := j.stringRule(k, path)
+	if hasRule && rule.priority > 0 {
+		return rule.fn
+	}
+
+	if fn, ok := j.pathStringFuncs[j.pathKey(path)]; ok {
+		return fn
+	}
+
+	if fn, ok := j.pathAssignFuncs[j.pathKey(path)]; ok {
+		return st.bind(fn)
+	}
+
+	if hasRule {
+		return rule.fn
+	}
+
+	if j.maskAssignFunc != nil {
+		return st.bind(j.maskAssignFunc)
+	}
+
+	return j.maskStringFunc
+}
+
+// integerFuncs returns MaskInt64Func or MaskIntFunc registered for the path or the common one,
+// path funcs take precedence and MaskInt64Func is preferred over MaskIntFunc
+func (j *JsonMask) integerFuncs(path string) (MaskInt64Func, MaskIntFunc) {
+	path = j.pathKey(path)
+	if fn, ok := j.pathInt64Funcs[path]; ok {
+		return fn, nil
+	}
+
+	if fn, ok := j.pathIntFuncs[path]; ok {
+		return nil, fn
+	}
+
+	if j.maskInt64Func != nil {
+		return j.maskInt64Func, nil
+	}
+
+	return nil, j.maskIntFunc
+}
+
+// float64Func returns MaskFloat64Func registered for the path or the common one
+func (j *JsonMask) float64Func(path string) MaskFloat64Func {
+	if fn, ok := j.pathFloat64Funcs[j.pathKey(path)]; ok {
+		return fn
+	}
+
+	return j.maskFloat64Func
+}
+
+// valueFunc returns MaskValueFunc registered for the path or the common one
+func (j *JsonMask) valueFunc(path string) MaskValueFunc {
+	if fn, ok := j.pathValueFuncs[j.pathKey(path)]; ok {
+		return fn
+	}
+
+	return j.maskValueFunc
+}
+
+// MaskTypeName masks the value of any type with its json type label ("<string>", "<number>", "<boolean>", "<null>")
+func MaskTypeName() MaskValueFunc {
+	return func(_ string, val any) (any, error) {
+		switch val.(type) {
+		case string:
+			return "<string>", nil
+		case float64, json.Number:
+			return "<number>", nil
+		case bool:
+			return "<boolean>", nil
+		case nil:
+			return "<null>", nil
+		case map[string]any, orderedObject:
+			return "<object>", nil
+		case []any:
+			return "<array>", nil
+		default:
+			return nil, fmt.Errorf("unknow type: %T", val)
+		}
+	}
+}
+
+// MaskFilledString masks the string length of the value with the same length or by passed length
+func MaskFilledString(maskChar string, length ...int) MaskStringFunc {
+	hasLen := len(length) > 0
+	return func(_, val string) (string, error) {
+		if hasLen {
+			return strings.Repeat(maskChar, length[0]), nil
+		}
+
+		return strings.Repeat(maskChar, utf8.RuneCountInString(val)), nil
+	}
+}
+
+// MaskCharClass masks only characters of the class with the mask char keeping the others ("ABC-123" -> "ABC-***"
+// for "digits"), the class is "digits", "letters" or "symbols" (punctuation and symbols) or several of them
+// separated by comma ("digits,letters"). Unknown classes make the func return ErrInvalidCharClass
+func MaskCharClass(class string, maskChar string) MaskStringFunc {
+	var (
+		classes []func(rune) bool
+		err     error
+	)
+	for _, name := range strings.Split(class, ",") {
+		switch strings.TrimSpace(name) {
+		case "digits":
+			classes = append(classes, unicode.IsDigit)
+		case "letters":
+			classes = append(classes, unicode.IsLetter)
+		case "symbols":
+			classes = append(classes, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) })
+		default:
+			err = fmt.Errorf("%w %q", ErrInvalidCharClass, name)
+		}
+	}
+
+	return func(_, val string) (string, error) {
+		if err != nil {
+			return "", err
+		}
+
+		var b strings.Builder
+		for _, r := range val {
+			masked := false
+			for _, is := range classes {
+				if is(r) {
+					masked = true
+					break
+				}
+			}
+
+			if masked {
+				b.WriteString(maskChar)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+
+		return b.String(), nil
+	}
+}
+
+// MaskFilledBytes masks the string like MaskFilledString but caps the result at maxBytes bytes,
+// so multibyte mask characters (•) don't exceed byte length limits of fixed-width columns
+func MaskFilledBytes(maskChar string, maxBytes int) MaskStringFunc {
+	return func(_, val string) (string, error) {
+		n := utf8.RuneCountInString(val)
+		if maskChar != "" && n*len(maskChar) > maxBytes {
+			n = maxBytes / len(maskChar)
+		}
+
+		if n < 0 {
+			n = 0
+		}
+
+		return strings.Repeat(maskChar, n), nil
+	}
+}
+
+// MaskFixedString masks the string with the fixed text regardless of the value
+func MaskFixedString(text string) MaskStringFunc {
+	return func(_, _ string) (string, error) {
+		return text, nil
+	}
+}
+
+// MaskPartialString masks the string keeping first head and last tail characters (4111********1111),
+// values not longer than head+tail are masked entirely, negative head and tail are treated as 0
+func MaskPartialString(head, tail int, maskChar string) MaskStringFunc {
+	if head < 0 {
+		head = 0
+	}
+
+	if tail < 0 {
+		tail = 0
+	}
+
+	return func(_, val string) (string, error) {
+		runes := []rune(val)
+		if len(runes) <= head+tail {
+			return strings.Repeat(maskChar, len(runes)), nil
+		}
+
+		return string(runes[:head]) + strings.Repeat(maskChar, len(runes)-head-tail) + string(runes[len(runes)-tail:]), nil
+	}
+}
+
+// SkipBlankString wraps fn so that blank values (empty, whitespace-only or control characters only)
+// are not masked: they are returned unchanged or replaced with the passed placeholder
+func SkipBlankString(fn MaskStringFunc, placeholder ...string) MaskStringFunc {
+	hasPlaceholder := len(placeholder) > 0
+	return func(path, val string) (string, error) {
+		if !isBlank(val) {
+			return fn(path, val)
+		}
+
+		if hasPlaceholder {
+			return placeholder[0], nil
+		}
+
+		return val, nil
+	}
+}
+
+// MaskNumericAwareString masks digits of numeric-looking strings keeping their separators ("+1 (555) 12-34" -> "+* (***) **-**"),
+// other strings are masked entirely like MaskFilledString
+func MaskNumericAwareString(maskChar string) MaskStringFunc {
+	return func(_, val string) (string, error) {
+		if !isNumericLike(val) {
+			return strings.Repeat(maskChar, utf8.RuneCountInString(val)), nil
+		}
+
+		var b strings.Builder
+		for _, r := range val {
+			if r >= '0' && r <= '9' {
+				b.WriteString(maskChar)
+				continue
+			}
+			b.WriteRune(r)
+		}
+
+		return b.String(), nil
+	}
+}
+
+// MaskLookup masks the string with its replacement from the table (department codes to masked codes),
+// values missing in the table are masked by fallback or left unchanged if fallback is nil
+func MaskLookup(table map[string]string, fallback MaskStringFunc) MaskStringFunc {
+	replacements := make(map[string]string, len(table))
+	for k, v := range table {
+		replacements[k] = v
+	}
+
+	return func(path, val string) (string, error) {
+		if replacement, ok := replacements[val]; ok {
+			return replacement, nil
+		}
+
+		if fallback == nil {
+			return val, ErrSkip
+		}
+
+		return fallback(path, val)
+	}
+}
+
+// OnlyUnder wraps fn so that it masks only values by the prefix xpath or beneath it (/payments, /payments[0]/card),
+// other values are left unchanged
+func OnlyUnder(prefix string, fn MaskStringFunc) MaskStringFunc {
+	prefix = strings.TrimSuffix(prefix, pathKey)
+	return func(path, val string) (string, error) {
+		if prefix != "" && path != prefix && !isUnder(path, prefix) {
+			return val, ErrSkip
+		}
+
+		return fn(path, val)
+	}
+}
+
+// OnlyMatching wraps fn so that it masks only values whose xpath matches re, other values are left unchanged
+func OnlyMatching(re *regexp.Regexp, fn MaskStringFunc) MaskStringFunc {
+	return func(path, val string) (string, error) {
+		if !re.MatchString(path) {
+			return val, ErrSkip
+		}
+
+		return fn(path, val)
+	}
+}
+
+// MaskHashString masks and hashes (sha1) a string
+func MaskHashString() MaskStringFunc {
+	return func(_, val string) (string, error) {
+		hash := sha1.Sum(([]byte)(val))
+		return hex.EncodeToString(hash[:]), nil
+	}
+}
+
+// MaskHashSaltedByPath masks and hashes (sha1) a string salted with the field path, so identical
+// values hash differently in different fields, array indices are not part of the salt
+func MaskHashSaltedByPath() MaskStringFunc {
+	return func(path, val string) (string, error) {
+		hash := sha1.Sum([]byte(stripIndices(path) + "\x00" + val))
+		return hex.EncodeToString(hash[:]), nil
+	}
+}
+
+// MaskQueryString masks values of the passed keys inside application/x-www-form-urlencoded content
+// (a=1&password=secret), values are filled with "*" by decoded length, other pairs are kept as is
+func MaskQueryString(keys ...string) MaskStringFunc {
+	maskKeys := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		maskKeys[key] = struct{}{}
+	}
+
+	return func(_, val string) (string, error) {
+		pairs := strings.Split(val, "&")
+		for i, pair := range pairs {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				continue
+			}
+
+			key, err := url.QueryUnescape(k)
+			if err != nil {
+				continue
+			}
+
+			if _, ok := maskKeys[key]; !ok {
+				continue
+			}
+
+			n := len(v)
+			if dv, err := url.QueryUnescape(v); err == nil {
+				n = utf8.RuneCountInString(dv)
+			}
+
+			pairs[i] = k + "=" + strings.Repeat("*", n)
+		}
+
+		return strings.Join(pairs, "&"), nil
+	}
+}
+
+// MaskCSVColumn masks zero-based columns of a delimited line (1,secret,3), values are filled with "*"
+// by length and out-of-range columns are ignored. Quoted fields aren't supported, a quoted delimiter splits the column
+func MaskCSVColumn(delim rune, columns ...int) MaskStringFunc {
+	sep := string(delim)
+	return func(_, val string) (string, error) {
+		cols := strings.Split(val, sep)
+		for _, c := range columns {
+			if c < 0 || c >= len(cols) {
+				continue
+			}
+
+			cols[c] = strings.Repeat("*", utf8.RuneCountInString(cols[c]))
+		}
+
+		return strings.Join(cols, sep), nil
+	}
+}
+
+// MaskRandomInt masks converts an integer (int) into a random number in range (default 1000)
+func MaskRandomInt(arg ...int) MaskIntFunc {
+	hasArg := len(arg) > 0
+	return func(_ string, val int) (int, error) {
+		rn := randomIntRange
+		if hasArg {
+			rn = arg[0]
+		}
+
+		return rand.Intn(rn), nil
+	}
+}
+
+// MaskRandomIntSameWidth converts an integer (int) into a random number with the same number of digits
+// and sign, single digits (including zero) are replaced with a random digit. The number is derived from
+// the seed and the original value, so the same value gets the same number
+func MaskRandomIntSameWidth(seed int64) MaskIntFunc {
+	return func(_ string, val int) (int, error) {
+		u := uint64(val)
+		if val < 0 {
+			u = -u
+		}
+
+		width := 1
+		lo := 1
+		for n := u / 10; n > 0; n /= 10 {
+			width++
+			lo *= 10
+		}
+
+		rnd := rand.New(rand.NewSource(seed ^ int64(u)))
+
+		res := rnd.Intn(10)
+		if width > 1 {
+			// numbers of the widest width are limited by the max int
+			span := math.MaxInt - lo + 1
+			if lo <= math.MaxInt/10 {
+				span = 9 * lo
+			}
+			res = lo + rnd.Intn(span)
+		}
+
+		if val < 0 {
+			res = -res
+		}
+
+		return res, nil
+	}
+}
+
+// MaskProportionalNoise converts a float (float64) into the value multiplied by a random factor within
+// [1-pct, 1+pct] (pct 0.1 is ±10%) to keep distributions of durations and measurements realistic. The factor is derived
+// from the seed and the original value, so the same value gets the same result
+func MaskProportionalNoise(pct float64, seed int64) MaskFloat64Func {
+	pct = math.Abs(pct)
+
+	return func(_ string, val float64) (float64, error) {
+		rnd := rand.New(rand.NewSource(seed ^ int64(math.Float64bits(val))))
+		return val * (1 + pct*(2*rnd.Float64()-1)), nil
+	}
+}
+
+// MaskRandomFloat64 converts a float64 to a random number in range (default 1000.3)
+// if you pass "1000.3" to arg, it sets a random number in the range of 0.000 to 999.999.
+// The range is validated once, the func returns ErrInvalidRange for a malformed range, see NewMaskRandomFloat64
+func MaskRandomFloat64(arg ...string) MaskFloat64Func {
+	rn := randomFloatRange
+	if len(arg) > 0 {
+		rn = arg[0]
+	}
+
+	fn, err := NewMaskRandomFloat64(rn)
+	if err != nil {
+		return func(_ string, _ float64) (float64, error) {
+			return 0, err
+		}
+	}
+
+	return fn
+}
+
+// NewMaskRandomFloat64 returns MaskRandomFloat64 func for the range ("1000.3") or ErrInvalidRange
+// if the range isn't a positive integer with optional number of decimals (up to 15)
+func NewMaskRandomFloat64(rn string) (MaskFloat64Func, error) {
+	intPart, fracPart, hasFrac := strings.Cut(rn, ".")
+	if !isDigits(intPart) || hasFrac && !isDigits(fracPart) {
+		return nil, fmt.Errorf("%w %q: must be <integer>[.<decimals>]", ErrInvalidRange, rn)
+	}
+
+	i, err := strconv.Atoi(intPart)
+	if err != nil || i <= 0 {
+		return nil, fmt.Errorf("%w %q: integer part must be positive", ErrInvalidRange, rn)
+	}
+
+	d := 0
+	if hasFrac {
+		d, err = strconv.Atoi(fracPart)
+	}
+
+	if err != nil || d > maxRandomFloatDecimals {
+		return nil, fmt.Errorf("%w %q: decimals must be at most %d", ErrInvalidRange, rn, maxRandomFloatDecimals)
+	}
+
+	dd := math.Pow10(d)
+	return func(_ string, _ float64) (float64, error) {
+		x := float64(int(rand.Float64() * float64(i) * dd))
+		return x / dd, nil
+	}, nil
+}
+
+// MaskSignificantFigures rounds a float64 to n significant figures (123456 -> 120000 for n = 2)
+func MaskSignificantFigures(n int) MaskFloat64Func {
+	return func(_ string, val float64) (float64, error) {
+		if n < 1 {
+			return 0, fmt.Errorf("significant figures must be positive: %d", n)
+		}
+
+		if val == 0 || math.IsNaN(val) || math.IsInf(val, 0) {
+			return val, nil
+		}
+
+		power := n - int(math.Ceil(math.Log10(math.Abs(val))))
+		if power < 0 {
+			mag := math.Pow10(-power)
+			return math.Round(val/mag) * mag, nil
+		}
+
+		mag := math.Pow10(power)
+		return math.Round(val*mag) / mag, nil
+	}
+}
+
+// MaskFloatPrefixDigits keeps the first keep digits of the integer part and zeroes the rest (1234567.8 -> 1200000 for keep = 2),
+// the sign is kept for non-zero results (-5.5 -> 0 for keep = 0) and values with no more than keep integer digits are returned unchanged
+func MaskFloatPrefixDigits(keep int) MaskFloat64Func {
+	return func(_ string, val float64) (float64, error) {
+		if keep < 0 {
+			return 0, fmt.Errorf("digits to keep must not be negative: %d", keep)
+		}
+
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return val, nil
+		}
+
+		ip := math.Trunc(math.Abs(val))
+		digits := len(strconv.FormatFloat(ip, 'f', -1, 64))
+		if ip == 0 || digits <= keep {
+			return val, nil
+		}
+
+		mag := math.Pow10(digits - keep)
+		res := math.Trunc(ip/mag) * mag
+		if res == 0 {
+			// the sign isn't kept for zero, -0 is marshaled as "-0"
+			return 0, nil
+		}
+
+		return math.Copysign(res, val), nil
+	}
+}
+
+// MaskIntRangeLabel replaces an integer with the label of its bucket for MaskValueFunc, bounds are ascending
+// inclusive upper bounds of the buckets and labels has one more entry for values above the last bound
+// (bounds [0, 10, 100] and labels ["0", "1-10", "11-100", ">100"] give 10 -> "1-10" and 101 -> ">100").
+// Other values are left unchanged, malformed bounds are validated once and the func returns ErrInvalidRange
+func MaskIntRangeLabel(bounds []int, labels []string) MaskValueFunc {
+	var err error
+	switch {
+	case len(labels) != len(bounds)+1:
+		err = fmt.Errorf("%w: want %d labels for %d bounds, got %d", ErrInvalidRange, len(bounds)+1, len(bounds), len(labels))
+	case !sort.IntsAreSorted(bounds):
+		err = fmt.Errorf("%w: bounds must be ascending", ErrInvalidRange)
+	}
+
+	bounds = append([]int(nil), bounds...)
+	labels = append([]string(nil), labels...)
+
+	return func(_ string, value any) (any, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		f, ok := value.(float64)
+		if !ok || !isInteger(f) {
+			return value, ErrSkip
+		}
+
+		i := sort.Search(len(bounds), func(i int) bool { return f <= float64(bounds[i]) })
+		return labels[i], nil
+	}
+}
+
+// copyFrame is a map or slice on the stack of deepCopy with its copy, keys are the keys of the map
+type copyFrame struct {
+	ptr  uintptr
+	path string
+	m    map[string]any
+	dm   map[string]any
+	keys []string
+	sl   []any
+	dsl  []any
+	i    int
+}
+
+// deepCopy method for copying maps and slices of decoded JSON value, ancestors are used to detect cycles.
+// The copying is iterative to handle deeply nested values
+func deepCopy(val any, path string, ancestors map[uintptr]struct{}) (any, error) {
+	var stack []copyFrame
+	res, err := copyValue(&stack, val, path, ancestors)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(stack) > 0 {
+		fr := &stack[len(stack)-1]
+		n := len(fr.sl)
+		if fr.m != nil {
+			n = len(fr.keys)
+		}
+
+		if fr.i == n {
+			delete(ancestors, fr.ptr)
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		i := fr.i
+		fr.i++
+
+		// fr is invalid after copyValue as it could push to the stack
+		if dm := fr.dm; dm != nil {
+			k := fr.keys[i]
+			if dm[k], err = copyValue(&stack, fr.m[k], fr.path+pathKey+k, ancestors); err != nil {
+				return nil, err
+			}
+		} else {
+			dsl := fr.dsl
+			if dsl[i], err = copyValue(&stack, fr.sl[i], fmt.Sprintf("%s[%d]", rootPath(fr.path), i), ancestors); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// copyValue returns the copy of value by path, maps and slices with items are pushed to the stack to copy their items.
+// Returns ErrCycle if the value is one of ancestors
+func copyValue(stack *[]copyFrame, val any, path string, ancestors map[uintptr]struct{}) (any, error) {
+	fr := copyFrame{path: path}
+	switch v := val.(type) {
+	case map[string]any:
+		fr.m, fr.dm = v, make(map[string]any, len(v))
+		if len(v) == 0 {
+			return fr.dm, nil
+		}
+
+		fr.keys = make([]string, 0, len(v))
+		for k := range v {
+			fr.keys = append(fr.keys, k)
+		}
+		fr.ptr = reflect.ValueOf(v).Pointer()
+	case []any:
+		fr.sl, fr.dsl = v, make([]any, len(v))
+		if len(v) == 0 {
+			return fr.dsl, nil
+		}
+		fr.ptr = reflect.ValueOf(v).Pointer()
+	default:
+		return v, nil
+	}
+
+	if _, ok := ancestors[fr.ptr]; ok {
+		return nil, fmt.Errorf("%s: %w", rootPath(path), ErrCycle)
+	}
+	ancestors[fr.ptr] = struct{}{}
+
+	*stack = append(*stack, fr)
+	if fr.dm != nil {
+		return fr.dm, nil
+	}
+
+	return fr.dsl, nil
+}
+
+// isBlank method for check string on containing only whitespace or control characters
+func isBlank(val string) bool {
+	for _, r := range val {
+		if !unicode.IsSpace(r) && !unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addKeys adds keys of the map to the set
+func addKeys[V any](set map[string]struct{}, m map[string]V) {
+	for k := range m {
+		set[k] = struct{}{}
+	}
+}
+
+// validatePath method for checking xpath on the grammar described in Validate
+func validatePath(path string) error {
+	if path == pathKey {
+		return nil
+	}
+
+	rest := path
+	if strings.HasPrefix(rest, pathKey+"[") {
+		// indices of root array
+		rest = rest[1:]
+		idx := strings.Index(rest, pathKey)
+		if idx < 0 {
+			idx = len(rest)
+		}
+
+		if err := validateIndices(rest[:idx]); err != nil {
+			return err
+		}
+
+		rest = rest[idx:]
+		if rest == "" {
+			return nil
+		}
+	}
+
+	if !strings.HasPrefix(rest, pathKey) {
+		return errors.New("must start with /")
+	}
+
+	for _, segment := range strings.Split(rest[1:], pathKey) {
+		key, indices, hasIndices := strings.Cut(segment, "[")
+		if key == "" {
+			return errors.New("empty segment")
+		}
+
+		if strings.Contains(key, "]") {
+			return errors.New("unbalanced brackets")
+		}
+
+		if hasIndices {
+			if err := validateIndices("[" + indices); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateIndices method for checking sequence of array indices ([0][1])
+func validateIndices(indices string) error {
+	for indices != "" {
+		if indices[0] != '[' {
+			return errors.New("unbalanced brackets")
+		}
+
+		end := strings.IndexByte(indices, ']')
+		if end < 0 {
+			return errors.New("unbalanced brackets")
+		}
+
+		if _, err := strconv.ParseUint(indices[1:end], 10, 0); err != nil {
+			return fmt.Errorf("invalid index %q", indices[1:end])
+		}
+
+		indices = indices[end+1:]
+	}
+
+	return nil
+}
+
+// rootPath method for printing path, the root value has path "/"
+func rootPath(path string) string {
+	if path == "" {
+		return pathKey
+	}
+
+	return path
+}
+
+// isNumericLike check value on containing digits and only number separators (.,-+/() and spaces)
+func isNumericLike(val string) bool {
+	hasDigit := false
+	for _, r := range val {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case strings.ContainsRune(".,-+/() ", r):
+		default:
+			return false
+		}
+	}
+
+	return hasDigit
+}
+
+// isUnder check path on being nested beneath the prefix xpath (/a/b and /a[0] are under /a)
+func isUnder(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) || len(path) == len(prefix) {
+		return false
+	}
+
+	c := path[len(prefix)]
+	return c == '/' || c == '[' || prefix == pathKey
+}
+
+// stripIndices method for removing array indices from the path (/a[0]/b[1] -> /a/b),
+// items of a root array lose their index segment (/[0]/a -> /a)
+func stripIndices(path string) string {
+	if !strings.Contains(path, "[") {
+		return path
+	}
+
+	var (
+		b     strings.Builder
+		inIdx bool
+	)
+	for _, r := range path {
+		switch {
+		case r == '[':
+			inIdx = true
+		case r == ']' && inIdx:
+			inIdx = false
+		case !inIdx:
+			b.WriteRune(r)
+		}
+	}
+
+	res := b.String()
+	if strings.HasPrefix(res, pathKey+pathKey) {
+		return res[1:]
+	}
+
+	return res
 }
 
-// isInteger method for check float value on integer
+// isInteger method for check float value on integer in int64 range
 func isInteger(val float64) bool {
-	return val == float64(int(val))
+	return val == math.Trunc(val) && val >= math.MinInt64 && val < math.MaxInt64
 }
@@ -1,7 +1,14 @@
 package jsonmask
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -83,38 +90,1141 @@ func TestNewJSONMask(t *testing.T) {
 			mask:    NewJSONMask("fieldA"),
 			rFuncs:  []interface{}{testMaskRandomFloat64(998.998)},
 			value:   `{"fieldA": 12345, "metadata": {"fieldA": 1.234, "fieldB": "valueB", "fieldC": "valueC"}}`,
-			expect:  `{"fieldA":12345,"metadata":{"fieldA":998.998,"fieldB":"valueB","fieldC":"valueC"}}`,
+			expect:  `{"fieldA":998.998,"metadata":{"fieldA":998.998,"fieldB":"valueB","fieldC":"valueC"}}`,
 			wantErr: false,
 		},
+		{
+			name:    "should skip masking of blank strings with tabs and unicode spaces",
+			mask:    NewJSONMask("fieldA"),
+			rFuncs:  []interface{}{SkipBlankString(MaskFilledString("*"))},
+			value:   `{"fieldA": "\t \t", "metadata": {"fieldA": "\u00a0\u2003", "fieldB": {"fieldA": "value"}}}`,
+			expect:  "{\"fieldA\":\"\\t \\t\",\"metadata\":{\"fieldA\":\"\u00a0\u2003\",\"fieldB\":{\"fieldA\":\"*****\"}}}",
+			wantErr: false,
+		},
+		{
+			name:    "should replace blank and control-character strings with placeholder",
+			mask:    NewJSONMask("fieldA", "fieldB", "fieldC"),
+			rFuncs:  []interface{}{SkipBlankString(MaskFilledString("*"), "<blank>")},
+			value:   `{"fieldA": "", "fieldB": "\u0000\u001f\n", "fieldC": " a "}`,
+			expect:  `{"fieldA":"\u003cblank\u003e","fieldB":"\u003cblank\u003e","fieldC":"***"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask query string values by keys",
+			mask:    NewJSONMask("query"),
+			rFuncs:  []interface{}{MaskQueryString("password", "token")},
+			value:   `{"query": "a=1&password=hunter2&b&token=x%3Dy=z&password=", "other": "password=hunter2"}`,
+			expect:  `{"other":"password=hunter2","query":"a=1\u0026password=*******\u0026b\u0026token=*****\u0026password="}`,
+			wantErr: false,
+		},
+		{
+			name:    "should hash primitive string root by root xpath",
+			mask:    NewJSONMask("/"),
+			rFuncs:  []interface{}{MaskHashString()},
+			value:   `"secret-token"`,
+			expect:  `"1ae0af3fe72b3ba394f9fa95a6cffc090d726c23"`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask primitive number root by root xpath",
+			mask:    NewJSONMask("/"),
+			rFuncs:  []interface{}{testMaskRandomInt(7)},
+			value:   `42`,
+			expect:  `7`,
+			wantErr: false,
+		},
+		{
+			name:    "should keep primitive root without root xpath",
+			mask:    NewJSONMask("key1"),
+			rFuncs:  []interface{}{MaskHashString()},
+			value:   `"secret-token"`,
+			expect:  `"secret-token"`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask objects inside root array",
+			mask:    NewJSONMask("key1"),
+			rFuncs:  []interface{}{MaskFilledString("*")},
+			value:   `[{"key1": "value1"}, "value2"]`,
+			expect:  `[{"key1":"******"},"value2"]`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask csv columns with trailing delimiter and out of range columns",
+			mask:    NewJSONMask("row", "line"),
+			rFuncs:  []interface{}{MaskCSVColumn(',', 1, 3, 10)},
+			value:   `{"row": "1,secret,3,", "line": "a,bb", "other": "1,secret,3"}`,
+			expect:  `{"line":"a,**","other":"1,secret,3","row":"1,******,3,"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask csv columns with custom delimiter",
+			mask:    NewJSONMask("row"),
+			rFuncs:  []interface{}{MaskCSVColumn(';', 0)},
+			value:   `{"row": "secret;1,2;3"}`,
+			expect:  `{"row":"******;1,2;3"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask one cell of two-dimensional array by xpath",
+			mask:    NewJSONMask("/grid[1][0]"),
+			rFuncs:  []interface{}{testMaskRandomInt(0)},
+			value:   `{"grid": [[1, 2], [3, 4]]}`,
+			expect:  `{"grid":[[1,2],[0,4]]}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask cells of nested arrays by xpath",
+			mask:    NewJSONMask("/grid[0][1][0]", "/grid[1][0]/key1"),
+			rFuncs:  []interface{}{MaskFilledString("*")},
+			value:   `{"grid": [["a", ["b", "c"]], [{"key1": "d"}, "e"]]}`,
+			expect:  `{"grid":[["a",["*","c"]],[{"key1":"*"},"e"]]}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask all cells of two-dimensional array by global field",
+			mask:    NewJSONMask("grid"),
+			rFuncs:  []interface{}{testMaskRandomInt(0)},
+			value:   `{"grid": [[1, 2], [3, 4]], "other": [[5]]}`,
+			expect:  `{"grid":[[0,0],[0,0]],"other":[[5]]}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask global field inside arrays of objects as in nested objects",
+			mask:    NewJSONMask("email"),
+			rFuncs:  []interface{}{MaskFilledString("*")},
+			value:   `{"users": [{"email": "a"}, {"email": "b"}], "admin": {"email": "c"}, "groups": [[{"email": "d"}]], "other": [{"name": "e"}]}`,
+			expect:  `{"admin":{"email":"*"},"groups":[[{"email":"*"}]],"other":[{"name":"e"}],"users":[{"email":"*"},{"email":"*"}]}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask global field inside root array of objects",
+			mask:    NewJSONMask("email"),
+			rFuncs:  []interface{}{MaskFilledString("*")},
+			value:   `[{"email": "a"}, {"name": "b"}]`,
+			expect:  `[{"email":"*"},{"name":"b"}]`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask integers exceeding 32-bit range with int64 func",
+			mask:    NewJSONMask("id"),
+			rFuncs:  []interface{}{MaskInt64Func(func(_ string, value int64) (int64, error) { return value + 1, nil })},
+			value:   `{"id": 3000000000, "list": {"id": [-3000000000, 1.5]}}`,
+			expect:  `{"id":3000000001,"list":{"id":[-2999999999,1.5]}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should prefer int64 func over int func",
+			mask:    NewJSONMask("id"),
+			rFuncs:  []interface{}{testMaskRandomInt(1), MaskInt64Func(func(_ string, value int64) (int64, error) { return 2, nil })},
+			value:   `{"id": 3000000000}`,
+			expect:  `{"id":2}`,
+			wantErr: false,
+		},
+		{
+			name:    "should pass exact integer beyond float64 precision to int64 func",
+			mask:    NewJSONMask("id"),
+			rFuncs:  []interface{}{MaskInt64Func(func(_ string, value int64) (int64, error) { return value, nil })},
+			value:   `{"id": 9007199254740993, "other": 9007199254740993}`,
+			expect:  `{"id":9007199254740993,"other":9007199254740993}`,
+			wantErr: false,
+		},
+		{
+			name:    "should fail for integer out of int64 range without float func",
+			mask:    NewJSONMask("id"),
+			rFuncs:  []interface{}{MaskInt64Func(func(_ string, value int64) (int64, error) { return 0, nil })},
+			value:   `{"id": 12345678901234567890}`,
+			wantErr: true,
+		},
+		{
+			name:    "should fail for exponent integer out of int range without float func",
+			mask:    NewJSONMask("id"),
+			rFuncs:  []interface{}{testMaskRandomInt(1)},
+			value:   `{"id": 1e19}`,
+			wantErr: true,
+		},
+		{
+			name: "should mask integer out of int64 range with float func",
+			mask: NewJSONMask("id"),
+			rFuncs: []interface{}{
+				MaskInt64Func(func(_ string, value int64) (int64, error) { return 0, nil }),
+				MaskFloat64Func(func(_ string, value float64) (float64, error) { return 1, nil }),
+			},
+			value:   `{"id": 1e19}`,
+			expect:  `{"id":1}`,
+			wantErr: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			if len(tt.rFuncs) > 0 {
+				for _, rFn := range tt.rFuncs {
+					switch fn := rFn.(type) {
+					case MaskStringFunc:
+						tt.mask.RegisterMaskStringFunc(fn)
+					case MaskIntFunc:
+						tt.mask.RegisterMaskIntFunc(fn)
+					case MaskInt64Func:
+						tt.mask.RegisterMaskInt64Func(fn)
+					case MaskFloat64Func:
+						tt.mask.RegisterMaskFloat64Func(fn)
+					}
+				}
+			}
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterPathMaskFunc(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		mask    func() *JsonMask
+		expect  string
+		wantErr bool
+	}{
+		{
+			name: "should apply different funcs for different array indices",
+			mask: func() *JsonMask {
+				m := NewJSONMask()
+				m.RegisterPathMaskStringFunc("/entries[0]/secret", MaskHashString())
+				m.RegisterPathMaskStringFunc("/entries[1]/secret", MaskFilledString("*"))
+				return m
+			},
+			value:   `{"entries": [{"secret": "a"}, {"secret": "b"}, {"secret": "c"}]}`,
+			expect:  `{"entries":[{"secret":"86f7e437faa5a7fce15d1ddcb9eaeaea377667b8"},{"secret":"*"},{"secret":"c"}]}`,
+			wantErr: false,
+		},
+		{
+			name: "should prefer path funcs over common funcs",
+			mask: func() *JsonMask {
+				m := NewJSONMask("secret", "count")
+				m.RegisterMaskStringFunc(MaskFilledString("*"))
+				m.RegisterMaskIntFunc(testMaskRandomInt(1))
+				m.RegisterPathMaskStringFunc("/entries[1]/secret", MaskHashString())
+				m.RegisterPathMaskIntFunc("/entries[1]/count", testMaskRandomInt(2))
+				m.RegisterPathMaskFloat64Func("/entries[1]/ratio", testMaskRandomFloat64(2.5))
+				return m
+			},
+			value:   `{"entries": [{"secret": "a", "count": 10, "ratio": 0.1}, {"secret": "b", "count": 20, "ratio": 0.2}]}`,
+			expect:  `{"entries":[{"count":1,"ratio":0.1,"secret":"*"},{"count":2,"ratio":2.5,"secret":"e9d71f5ee7c92d6dc9e92ffdad17b8bd49418f98"}]}`,
+			wantErr: false,
+		},
+		{
+			name: "should prefer path int64 funcs over common int64 funcs",
+			mask: func() *JsonMask {
+				m := NewJSONMask("count")
+				m.RegisterMaskInt64Func(func(_ string, value int64) (int64, error) { return value * 2, nil })
+				m.RegisterPathMaskIntFunc("/entries[0]/count", testMaskRandomInt(1))
+				m.RegisterPathMaskInt64Func("/entries[1]/count", func(_ string, value int64) (int64, error) { return value + 1, nil })
+				return m
+			},
+			value:   `{"entries": [{"count": 10}, {"count": 4294967296}, {"count": 3000000000}]}`,
+			expect:  `{"entries":[{"count":1},{"count":4294967297},{"count":6000000000}]}`,
+			wantErr: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := tt.mask().Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterObjectFunc(t *testing.T) {
+	splitName := func(object map[string]any) error {
+		name, ok := object["fullName"].(string)
+		if !ok {
+			return nil
+		}
+
+		first, last, _ := strings.Cut(name, " ")
+		object["firstName"], object["lastName"] = first, last
+		delete(object, "fullName")
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:    "should split field into two masked fields",
+			mask:    NewJSONMask("firstName", "lastName"),
+			value:   `{"user": {"id": 1, "fullName": "John Doe"}, "admin": {"fullName": "Jane Roe"}}`,
+			expect:  `{"admin":{"fullName":"Jane Roe"},"user":{"firstName":"****","id":1,"lastName":"***"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should split field keeping key order",
+			mask:    NewJSONMaskWithOptions(WithFields("firstName", "lastName"), WithOrderedKeys()),
+			value:   `{"user": {"id": 1, "fullName": "John Doe", "age": 30}}`,
+			expect:  `{"user":{"id":1,"age":30,"firstName":"****","lastName":"***"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should split field with verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithFields("firstName", "lastName"), WithCopyUnmatchedVerbatim()),
+			value:   `{"id": 1.0, "user": {"fullName": "John Doe"}}`,
+			expect:  `{"id": 1.0, "user": {"firstName":"****","lastName":"***"}}`,
+			wantErr: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterObjectFunc("/user", splitName)
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskHashSaltedByPath(t *testing.T) {
+	mask := NewJSONMask("phone", "fax")
+	mask.RegisterMaskStringFunc(MaskHashSaltedByPath())
+
+	got, err := mask.Mask(`{"phone": "555-1234", "fax": "555-1234", "contacts": [{"phone": "555-1234"}, {"phone": "555-1234"}]}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var res struct {
+		Phone    string `json:"phone"`
+		Fax      string `json:"fax"`
+		Contacts []struct {
+			Phone string `json:"phone"`
+		} `json:"contacts"`
+	}
+	if err := json.Unmarshal([]byte(got), &res); err != nil {
+		t.Fatalf("json unmarshal: %v", err)
+	}
+
+	if res.Phone == "555-1234" || res.Phone == res.Fax {
+		t.Errorf("Process() phone = %v, fax = %v, want different hashes", res.Phone, res.Fax)
+	}
+	if res.Contacts[0].Phone != res.Contacts[1].Phone {
+		t.Errorf("Process() contacts = %v, want equal hashes for the same field", res.Contacts)
+	}
+	if res.Contacts[0].Phone == res.Phone {
+		t.Errorf("Process() contacts phone = %v, want different from /phone hash", res.Contacts[0].Phone)
+	}
+}
+
+func TestMaskTypeName(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		mask    func() *JsonMask
+		expect  string
+		wantErr bool
+	}{
+		{
+			name: "should replace all leaf types with type names",
+			mask: func() *JsonMask {
+				m := NewJSONMask("s", "n", "b", "z", "list")
+				m.RegisterMaskValueFunc(MaskTypeName())
+				return m
+			},
+			value:   `{"s": "a", "n": 1.5, "b": true, "z": null, "list": ["a", 2, false, null], "other": "c"}`,
+			expect:  `{"b":"\u003cboolean\u003e","list":["\u003cstring\u003e","\u003cnumber\u003e","\u003cboolean\u003e","\u003cnull\u003e"],"n":"\u003cnumber\u003e","other":"c","s":"\u003cstring\u003e","z":"\u003cnull\u003e"}`,
+			wantErr: false,
+		},
+		{
+			name: "should replace nested values of global field and prefer value func over typed funcs",
+			mask: func() *JsonMask {
+				m := NewJSONMask("user")
+				m.RegisterMaskStringFunc(MaskFilledString("*"))
+				m.RegisterMaskValueFunc(MaskTypeName())
+				return m
+			},
+			value:   `{"user": {"name": "a", "age": 30, "tags": [{"id": 1}]}, "name": "b"}`,
+			expect:  `{"name":"b","user":{"age":"\u003cnumber\u003e","name":"\u003cstring\u003e","tags":[{"id":"\u003cnumber\u003e"}]}}`,
+			wantErr: false,
+		},
+		{
+			name: "should apply path value func only by xpath",
+			mask: func() *JsonMask {
+				m := NewJSONMask("name")
+				m.RegisterMaskStringFunc(MaskFilledString("*"))
+				m.RegisterPathMaskValueFunc("/user/active", MaskTypeName())
+				return m
+			},
+			value:   `{"user": {"name": "a", "active": true}, "active": false}`,
+			expect:  `{"active":false,"user":{"active":"\u003cboolean\u003e","name":"*"}}`,
+			wantErr: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := tt.mask().Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskNumericAwareString(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   func() *JsonMask
+		expect string
+	}{
+		{
+			name: "should mask integer-valued strings by string func without numeric strings option",
+			mask: func() *JsonMask {
+				m := NewJSONMask("id", "phone", "name", "count")
+				m.RegisterMaskStringFunc(MaskFilledString("*"))
+				m.RegisterMaskIntFunc(testMaskRandomInt(1))
+				return m
+			},
+			expect: `{"count":1,"id":"***","name":"*****","phone":"**************"}`,
+		},
+		{
+			name: "should mask digits of numeric-looking strings",
+			mask: func() *JsonMask {
+				m := NewJSONMask("id", "phone", "name", "count")
+				m.RegisterMaskStringFunc(MaskNumericAwareString("*"))
+				return m
+			},
+			expect: `{"count":5,"id":"***","name":"*****","phone":"+* (***) **-**"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := tt.mask().Mask(`{"id": "123", "phone": "+1 (555) 12-34", "name": "bob-1", "count": 5}`)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskCharClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		class   string
+		value   string
+		expect  string
+		wantErr error
+	}{
+		{name: "should mask only digits", class: "digits", value: "ABC-123", expect: "ABC-***"},
+		{name: "should mask only letters", class: "letters", value: "ABC-123", expect: "***-123"},
+		{name: "should mask only symbols", class: "symbols", value: "a-b+c d", expect: "a*b*c d"},
+		{name: "should mask several classes", class: "digits, letters", value: "AБ-1 2", expect: "**-* *"},
+		{name: "should keep value without class characters", class: "digits", value: "ABC", expect: "ABC"},
+		{name: "should return error for unknown class", class: "digits,emoji", value: "A1", wantErr: ErrInvalidCharClass},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := MaskCharClass(tt.class, "*")("/", tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskFilledBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		maskChar string
+		maxBytes int
+		value    string
+		expect   string
+	}{
+		{name: "should mask ascii within the budget", maskChar: "*", maxBytes: 10, value: "secret", expect: "******"},
+		{name: "should cap ascii at the budget", maskChar: "*", maxBytes: 4, value: "secret", expect: "****"},
+		{name: "should cap multibyte mask char at the budget", maskChar: "•", maxBytes: 10, value: "secret", expect: "•••"},
+		{name: "should count runes of multibyte value", maskChar: "•", maxBytes: 100, value: "пароль", expect: "••••••"},
+		{name: "should return empty string for too small budget", maskChar: "•", maxBytes: 2, value: "secret", expect: ""},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := MaskFilledBytes(tt.maskChar, tt.maxBytes)("/", tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect || len(got) > tt.maxBytes {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskPartialString(t *testing.T) {
+	tests := []struct {
+		name   string
+		head   int
+		tail   int
+		value  string
+		expect string
+	}{
+		{name: "should keep head and tail", head: 4, tail: 4, value: "4111111111111111", expect: "4111********1111"},
+		{name: "should mask value not longer than head and tail", head: 2, tail: 2, value: "1234", expect: "****"},
+		{name: "should count runes of multibyte value", head: 1, tail: 1, value: "пароль", expect: "п****ь"},
+		{name: "should treat negative head as 0", head: -2, tail: 2, value: "secret", expect: "****et"},
+		{name: "should treat negative tail as 0", head: 2, tail: -2, value: "secret", expect: "se****"},
+		{name: "should treat negative head and tail as 0", head: -1, tail: -1, value: "secret", expect: "******"},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := MaskPartialString(tt.head, tt.tail, "*")("/", tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskLookup(t *testing.T) {
+	table := map[string]string{"FIN": "D01", "HR": "D02"}
+
+	tests := []struct {
+		name   string
+		fn     MaskStringFunc
+		expect string
+	}{
+		{
+			name:   "should replace values from the table and mask others by fallback",
+			fn:     MaskLookup(table, MaskFilledString("*")),
+			expect: `{"dept":["D01","D02","***"],"name":"bob"}`,
+		},
+		{
+			name:   "should leave values missing in the table without fallback",
+			fn:     MaskLookup(table, nil),
+			expect: `{"dept":["D01","D02","OPS"],"name":"bob"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("dept")
+			mask.RegisterMaskStringFunc(tt.fn)
+
+			got, err := mask.Mask(`{"dept": ["FIN", "HR", "OPS"], "name": "bob"}`)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestOnlyUnder(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     MaskStringFunc
+		expect string
+	}{
+		{
+			name:   "should mask only by the prefix xpath",
+			fn:     OnlyUnder("/payments", MaskFilledString("*")),
+			expect: `{"payments":[{"card":"*"},{"card":"*"}],"paymentsOld":{"card":"c"},"user":{"card":"d"}}`,
+		},
+		{
+			name:   "should mask only by the prefix xpath with trailing slash",
+			fn:     OnlyUnder("/payments/", MaskFilledString("*")),
+			expect: `{"payments":[{"card":"*"},{"card":"*"}],"paymentsOld":{"card":"c"},"user":{"card":"d"}}`,
+		},
+		{
+			name:   "should mask only by xpath matching the regexp",
+			fn:     OnlyMatching(regexp.MustCompile(`^/payments(Old)?\b`), MaskFilledString("*")),
+			expect: `{"payments":[{"card":"*"},{"card":"*"}],"paymentsOld":{"card":"*"},"user":{"card":"d"}}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("card")
+			mask.RegisterMaskStringFunc(tt.fn)
+
+			got, err := mask.Mask(`{"payments": [{"card": "a"}, {"card": "b"}], "paymentsOld": {"card": "c"}, "user": {"card": "d"}}`)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskSignificantFigures(t *testing.T) {
+	tests := []struct {
+		n       int
+		value   float64
+		expect  float64
+		wantErr bool
+	}{
+		{n: 3, value: 0.00012345, expect: 0.000123},
+		{n: 2, value: 123456, expect: 120000},
+		{n: 4, value: 123456, expect: 123500},
+		{n: 1, value: -0.0456, expect: -0.05},
+		{n: 3, value: 1.23456, expect: 1.23},
+		{n: 3, value: 0, expect: 0},
+		{n: 0, value: 1.5, wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%v(%d)", i, tt.value, tt.n), func(t *testing.T) {
+			got, err := MaskSignificantFigures(tt.n)("", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MaskSignificantFigures() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("MaskSignificantFigures() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskOnceForSeveralRules(t *testing.T) {
+	var calls int
+	mask := NewJSONMask("secret", "/user/secret", "user")
+	mask.RegisterMaskStringFunc(func(path, value string) (string, error) {
+		calls++
+		return value + "*", nil
+	})
+
+	got, err := mask.Mask(`{"user": {"secret": "a"}, "secret": "b"}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"secret":"b*","user":{"secret":"a*"}}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+	if calls != 2 {
+		t.Errorf("Process() calls = %v, want 2", calls)
+	}
+}
+
+func TestRegisterDefaultStringFunc(t *testing.T) {
+	mask := NewJSONMask("password", "/user/email")
+	mask.RegisterMaskStringFunc(MaskHashString())
+	mask.RegisterDefaultStringFunc(func(path, value string) (string, error) {
+		if len(value) > 2 {
+			return value[:2] + "...", nil
+		}
+
+		return value, nil
+	})
+
+	got, err := mask.Mask(`{"password": "pass", "name": "HelloWorld", "user": {"email": "a@b.c", "city": "Paris", "age": 20}}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"name":"He...","password":"9d4e1e23bd5b727046a9e3b4b7db57bd8d6ee684","user":{"age":20,"city":"Pa...","email":"e2d6a40c8b4d3e6a2779ba1802ef29d13940a051"}}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
+
+func TestErrSkip(t *testing.T) {
+	mask := NewJSONMask("email", "age", "score")
+	mask.RegisterMaskStringFunc(func(path, value string) (string, error) {
+		if strings.HasSuffix(value, "@example.com") {
+			return "", ErrSkip
+		}
+
+		return "***", nil
+	})
+	mask.RegisterMaskIntFunc(func(path string, value int) (int, error) {
+		if value < 18 {
+			return 0, ErrSkip
+		}
+
+		return 0, nil
+	})
+	mask.RegisterMaskFloat64Func(func(path string, value float64) (float64, error) {
+		if strings.HasPrefix(path, "/users[0]") || !strings.HasSuffix(path, "/score") {
+			return 0, ErrSkip
+		}
+
+		return 0.5, nil
+	})
+
+	got, err := mask.Mask(`{"users": [{"email": "a@example.com", "age": 12, "score": 1.1}, {"email": "b@corp.com", "age": 30, "score": 2.2}]}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"users":[{"age":12,"email":"a@example.com","score":1.1},{"age":0,"email":"***","score":0.5}]}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
+
+func TestMaskAny(t *testing.T) {
+	mask := NewJSONMask("key1")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	value := map[string]any{"key1": "value1", "list": []any{map[string]any{"key1": "value2"}, "value3"}}
+
+	got, err := mask.MaskAny(value)
+	if err != nil {
+		t.Fatalf("MaskAny() error = %v", err)
+	}
+
+	expect := map[string]any{"key1": "******", "list": []any{map[string]any{"key1": "******"}, "value3"}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("MaskAny() got = %v, want %v", got, expect)
+	}
+	if !reflect.DeepEqual(value, expect) {
+		t.Errorf("MaskAny() value = %v, want masked in place %v", value, expect)
+	}
+}
+
+func TestMaskFloatWholeResult(t *testing.T) {
+	mask := NewJSONMask("count", "ratio", "big")
+	mask.RegisterMaskFloat64Func(func(_ string, value float64) (float64, error) { return value * 2, nil })
+
+	got, err := mask.MaskAny(map[string]any{"count": float64(5), "ratio": 2.5, "big": 1e20})
+	if err != nil {
+		t.Fatalf("MaskAny() error = %v", err)
+	}
+
+	expect := map[string]any{"count": int64(10), "ratio": float64(5), "big": 2e20}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("MaskAny() got = %#v, want %#v", got, expect)
+	}
+
+	res, err := mask.Mask(`{"count": 5, "ratio": 2.5, "big": 100000000000000000000}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if want := `{"big":200000000000000000000,"count":10,"ratio":5}`; res != want {
+		t.Errorf("Process() got = %v, want %v", res, want)
+	}
+}
+
+func TestMaskKeepsNumberRepresentation(t *testing.T) {
+	mask := NewJSONMask("name", "/masked")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+	mask.RegisterMaskFloat64Func(testMaskRandomFloat64(1.5))
+
+	got, err := mask.Mask(`{"name": "a", "ratio": 0.10, "pi": 3.14159265358979323846264338327950, "big": 12345678901234567890, "exp": 1e3, "whole": 1.0, "huge": 1e400, "masked": 0.1}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"big":12345678901234567890,"exp":1e3,"huge":1e400,"masked":1.5,"name":"*","pi":3.14159265358979323846264338327950,"ratio":0.10,"whole":1.0}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
+
+func TestMaskNumberOutOfFloatRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:    "should fail for matched number with float func",
+			mask:    NewJSONMask("a"),
+			value:   `{"a":1e400}`,
+			wantErr: true,
+		},
+		{
+			name:    "should fail for matched number with verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithFields("a"), WithCopyUnmatchedVerbatim()),
+			value:   `{"a": -1e400}`,
+			wantErr: true,
+		},
+		{
+			name:   "should keep unmatched number",
+			mask:   NewJSONMask("b"),
+			value:  `{"a":1e400,"b":0.5}`,
+			expect: `{"a":1e400,"b":1.5}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskFloat64Func(testMaskRandomFloat64(1.5))
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrNumberRange) {
+					t.Errorf("Process() error = %v, want %v", err, ErrNumberRange)
+				}
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskNumberPrecisionClass(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   func() *JsonMask
+		expect string
+	}{
+		{
+			name: "should mask integers by float func only and keep whole results integer",
+			mask: func() *JsonMask {
+				m := NewJSONMask("count", "ratio")
+				m.RegisterMaskFloat64Func(func(_ string, value float64) (float64, error) { return math.Round(value / 10), nil })
+				return m
+			},
+			expect: `{"count":12,"ratio":0,"size":2.5}`,
+		},
+		{
+			name: "should mask integers by int func and floats by float func",
+			mask: func() *JsonMask {
+				m := NewJSONMask("count", "ratio", "size")
+				m.RegisterMaskIntFunc(testMaskRandomInt(7))
+				m.RegisterMaskFloat64Func(testMaskRandomFloat64(0.5))
+				return m
+			},
+			expect: `{"count":7,"ratio":0.5,"size":0.5}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := tt.mask().Mask(`{"count": 123, "ratio": 1.5, "size": 2.5}`)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskStruct(t *testing.T) {
+	type contact struct {
+		Email string `json:"email"`
+		Phone string `json:"phone,omitempty"`
+	}
+	type user struct {
+		Name     string    `json:"name"`
+		Age      int       `json:"age"`
+		Contacts []contact `json:"contacts"`
+		Manager  *user     `json:"manager,omitempty"`
+	}
+
+	mask := NewJSONMask("email", "/contacts[0]/phone", "/manager/name")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	src := user{
+		Name:     "bob",
+		Age:      30,
+		Contacts: []contact{{Email: "a@b.c", Phone: "123"}, {Email: "d@e.f", Phone: "456"}},
+		Manager:  &user{Name: "alice", Contacts: []contact{{Email: "g@h.i"}}},
+	}
+
+	var dst user
+	if err := mask.MaskStruct(src, &dst); err != nil {
+		t.Fatalf("MaskStruct() error = %v", err)
+	}
+
+	expect := user{
+		Name:     "bob",
+		Age:      30,
+		Contacts: []contact{{Email: "*****", Phone: "***"}, {Email: "*****", Phone: "456"}},
+		Manager:  &user{Name: "*****", Contacts: []contact{{Email: "*****"}}},
+	}
+	if !reflect.DeepEqual(dst, expect) {
+		t.Errorf("MaskStruct() got = %+v, want %+v", dst, expect)
+	}
+	if src.Contacts[0].Email != "a@b.c" {
+		t.Errorf("MaskStruct() src = %+v, want unchanged", src)
+	}
+
+	var wrong struct {
+		Email int `json:"email"`
+	}
+	if err := mask.MaskStruct(contact{Email: "a@b.c"}, &wrong); err == nil {
+		t.Errorf("MaskStruct() error = nil, want error for mismatched dst type")
+	}
+}
+
+func TestMaskAnyCopy(t *testing.T) {
+	mask := NewJSONMask("key1")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	value := map[string]any{"key1": "value1", "list": []any{map[string]any{"key1": "value2"}, "value3"}}
+	original := map[string]any{"key1": "value1", "list": []any{map[string]any{"key1": "value2"}, "value3"}}
+
+	got, err := mask.MaskAnyCopy(value)
+	if err != nil {
+		t.Fatalf("MaskAnyCopy() error = %v", err)
+	}
+
+	expect := map[string]any{"key1": "******", "list": []any{map[string]any{"key1": "******"}, "value3"}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("MaskAnyCopy() got = %v, want %v", got, expect)
+	}
+	if !reflect.DeepEqual(value, original) {
+		t.Errorf("MaskAnyCopy() value = %v, want unchanged %v", value, original)
+	}
+}
+
+func TestMaskFloatPrefixDigits(t *testing.T) {
+	tests := []struct {
+		keep    int
+		value   float64
+		expect  float64
+		wantErr bool
+	}{
+		{keep: 2, value: 1234567, expect: 1200000},
+		{keep: 3, value: 98765.4321, expect: 98700},
+		{keep: 2, value: -1234567, expect: -1200000},
+		{keep: 4, value: 123, expect: 123},
+		{keep: 3, value: -12.5, expect: -12.5},
+		{keep: 0, value: 987, expect: 0},
+		{keep: 0, value: -5.5, expect: 0},
+		{keep: 1, value: -0.5, expect: -0.5},
+		{keep: -1, value: 1, wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%v(%d)", i, tt.value, tt.keep), func(t *testing.T) {
+			got, err := MaskFloatPrefixDigits(tt.keep)("", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MaskFloatPrefixDigits() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect || math.Signbit(got) != math.Signbit(tt.expect) {
+				t.Errorf("MaskFloatPrefixDigits() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestNewMaskRandomFloat64(t *testing.T) {
+	tests := []struct {
+		name    string
+		rn      string
+		max     float64
+		wantErr bool
+	}{
+		{name: "should accept default range", rn: "1000.3", max: 1000},
+		{name: "should accept range without decimals", rn: "10", max: 10},
+		{name: "should reject trailing dot", rn: "1000.", wantErr: true},
+		{name: "should reject missing integer part", rn: ".3", wantErr: true},
+		{name: "should reject several dots", rn: "1.2.3", wantErr: true},
+		{name: "should reject non-numeric range", rn: "abc", wantErr: true},
+		{name: "should reject negative range", rn: "-10.2", wantErr: true},
+		{name: "should reject zero range", rn: "0.2", wantErr: true},
+		{name: "should reject too many decimals", rn: "10.16", wantErr: true},
+		{name: "should reject empty range", rn: "", wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			fn, err := NewMaskRandomFloat64(tt.rn)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidRange) {
+					t.Errorf("Process() error = %v, want %v", err, ErrInvalidRange)
+				}
+				if _, fnErr := MaskRandomFloat64(tt.rn)("/", 1); !errors.Is(fnErr, ErrInvalidRange) {
+					t.Errorf("Process() func error = %v, want %v", fnErr, ErrInvalidRange)
+				}
+				return
+			}
+
+			got, err := fn("/", 1)
+			if err != nil || got < 0 || got >= tt.max {
+				t.Errorf("Process() got = %v, error = %v, want in range [0, %v)", got, err, tt.max)
+			}
+		})
+	}
+}
+
+func TestMaskAnyCycle(t *testing.T) {
+	mask := NewJSONMask("key1")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	cyclic := map[string]any{"key1": "value1"}
+	cyclic["self"] = map[string]any{"parent": cyclic}
+
+	list := []any{"value1", nil}
+	list[1] = list
+
+	shared := map[string]any{"key1": "value1"}
+	acyclic := map[string]any{"a": []any{1.0, 2.0}, "b": map[string]any{"c": shared}, "d": shared}
+
+	tests := []struct {
+		name    string
+		value   any
+		wantErr error
+	}{
+		{name: "should return error for self-referential map", value: cyclic, wantErr: ErrCycle},
+		{name: "should return error for self-referential slice", value: map[string]any{"list": list}, wantErr: ErrCycle},
+		{name: "should not return error for shared values without cycle", value: acyclic},
 	}
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
-			if len(tt.rFuncs) > 0 {
-				for _, rFn := range tt.rFuncs {
-					switch fn := rFn.(type) {
-					case MaskStringFunc:
-						tt.mask.RegisterMaskStringFunc(fn)
-					case MaskIntFunc:
-						tt.mask.RegisterMaskIntFunc(fn)
-					case MaskFloat64Func:
-						tt.mask.RegisterMaskFloat64Func(fn)
-					}
-				}
+			if _, err := mask.MaskAnyCopy(tt.value); !errors.Is(err, tt.wantErr) {
+				t.Errorf("MaskAnyCopy() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if _, err := mask.MaskAny(tt.value); !errors.Is(err, tt.wantErr) {
+				t.Errorf("MaskAny() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
 
-			got, err := tt.mask.Mask(tt.value)
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		wantErr bool
+	}{
+		{name: "should accept valid xpaths", mask: NewJSONMask("key1", "/", "/a/b", "/a[0]/b[1][2]", "[0]/a", "/[0]/a", "/[1][0]", "/a b/c-d")},
+		{name: "should accept valid prefix", mask: NewJSONMaskWithOptions(WithFieldsUnder("/user", "ssn"))},
+		{name: "should reject empty segment", mask: NewJSONMask("/a//b"), wantErr: true},
+		{name: "should reject trailing slash", mask: NewJSONMask("/a/b/"), wantErr: true},
+		{name: "should reject missing leading slash", mask: NewJSONMask("a/b"), wantErr: true},
+		{name: "should reject key after root array index", mask: NewJSONMask("/[0]a"), wantErr: true},
+		{name: "should reject unclosed bracket", mask: NewJSONMask("/a[0/b"), wantErr: true},
+		{name: "should reject unopened bracket", mask: NewJSONMask("/a0]/b"), wantErr: true},
+		{name: "should reject non-numeric index", mask: NewJSONMask("/a[x]"), wantErr: true},
+		{name: "should reject text after index", mask: NewJSONMask("/a[0]b"), wantErr: true},
+		{name: "should reject empty index", mask: NewJSONMask("/a[]"), wantErr: true},
+		{name: "should reject malformed prefix", mask: NewJSONMaskWithOptions(WithFieldsUnder("/user//x", "ssn")), wantErr: true},
+		{name: "should accept valid key glob", mask: NewJSONMaskWithOptions(WithKeyGlob("user_*_token", `a\*`))},
+		{name: "should reject malformed key glob", mask: NewJSONMaskWithOptions(WithKeyGlob("user_[")), wantErr: true},
+		{name: "should accept valid option xpaths", mask: NewJSONMaskWithOptions(WithPathType("/a/b", TypeString), WithCollapseArrays("/a[0]"), WithTruncateArrays(1, "/"))},
+		{name: "should reject malformed xpath of path type", mask: NewJSONMaskWithOptions(WithPathType("/a//b", TypeString)), wantErr: true},
+		{name: "should reject malformed xpath of collapsed arrays", mask: NewJSONMaskWithOptions(WithCollapseArrays("a/b")), wantErr: true},
+		{name: "should reject malformed xpath of hashed subtree", mask: NewJSONMaskWithOptions(WithHashSubtree("/a[x]")), wantErr: true},
+		{name: "should reject malformed xpath of truncated arrays", mask: NewJSONMaskWithOptions(WithTruncateArrays(1, "/a/")), wantErr: true},
+		{name: "should reject malformed xpath of shuffled arrays", mask: NewJSONMaskWithOptions(WithShuffleArrays(1, "/a[0")), wantErr: true},
+		{name: "should reject malformed xpath of digit arrays", mask: NewJSONMaskWithOptions(WithDigitArrayFields("/a[]")), wantErr: true},
+		{name: "should reject malformed xpath of redacted block", mask: NewJSONMaskWithOptions(WithRedactedBlock("_r", "/a//b")), wantErr: true},
+		{name: "should reject malformed xpath of object func", mask: func() *JsonMask {
+			m := NewJSONMask()
+			m.RegisterObjectFunc("/a//b", func(map[string]any) error { return nil })
+			return m
+		}(), wantErr: true},
+		{name: "should reject malformed xpath of masked object func", mask: func() *JsonMask {
+			m := NewJSONMask()
+			m.RegisterMaskedObjectFunc("/a]", func(map[string]any) error { return nil })
+			return m
+		}(), wantErr: true},
+		{name: "should reject malformed xpath of array func", mask: func() *JsonMask {
+			m := NewJSONMask()
+			m.RegisterArrayFunc("/a[0]b", func(_ string, elems []any) ([]any, error) { return elems, nil })
+			return m
+		}(), wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			err := tt.mask.Validate()
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
-				return
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if got != tt.expect {
-				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			if err != nil && !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("Validate() error = %v, want ErrInvalidPath", err)
 			}
 		})
 	}
+
+	err := NewJSONMask("/a//b", "/c[", "/ok").Validate()
+	if err == nil || !strings.Contains(err.Error(), `"/a//b"`) || !strings.Contains(err.Error(), `"/c["`) {
+		t.Errorf("Validate() error = %v, want all malformed paths reported", err)
+	}
+}
+
+func TestMaskUnchanged(t *testing.T) {
+	mask := NewJSONMask("password")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	value := `{"name": "HelloWorld",  "metadata": {"ratio": 1.50, "list": [1, 2]}}`
+
+	got, err := mask.Mask(value)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if got != value {
+		t.Errorf("Process() got = %v, want unchanged %v", got, value)
+	}
+
+	b := []byte(value)
+	gotBytes, err := mask.MaskBytes(b)
+	if err != nil {
+		t.Fatalf("MaskBytes() error = %v", err)
+	}
+	if &gotBytes[0] != &b[0] {
+		t.Errorf("MaskBytes() got = %s, want the same slice", gotBytes)
+	}
+
+	gotBytes, err = mask.MaskBytes([]byte(`{"password": "pass",  "name": "HelloWorld"}`))
+	if err != nil {
+		t.Fatalf("MaskBytes() error = %v", err)
+	}
+	if expect := `{"name":"HelloWorld","password":"****"}`; string(gotBytes) != expect {
+		t.Errorf("MaskBytes() got = %s, want %v", gotBytes, expect)
+	}
 }
 
-// BenchmarkNewJSONMaskHashString-16    	  343420	      3341 ns/op	    1929 B/op	      47 allocs/op
+// BenchmarkNewJSONMaskHashString-16    	  123904	      9232 ns/op	    2224 B/op	      38 allocs/op
 func BenchmarkNewJSONMaskHashString(b *testing.B) {
 	var (
 		mask = NewJSONMask("fieldA")
@@ -130,7 +1240,7 @@ func BenchmarkNewJSONMaskHashString(b *testing.B) {
 	}
 }
 
-// BenchmarkNewJSONMaskFilledString-16    	  335380	      3125 ns/op	    1771 B/op	      46 allocs/op
+// BenchmarkNewJSONMaskFilledString-16    	  134902	      8866 ns/op	    2072 B/op	      37 allocs/op
 func BenchmarkNewJSONMaskFilledString(b *testing.B) {
 	var (
 		mask = NewJSONMask("fieldA")
@@ -146,7 +1256,7 @@ func BenchmarkNewJSONMaskFilledString(b *testing.B) {
 	}
 }
 
-// BenchmarkNewJSONMaskInt-16    	  355957	      3167 ns/op	    1717 B/op	      44 allocs/op
+// BenchmarkNewJSONMaskInt-16    	  129910	      8918 ns/op	    2021 B/op	      36 allocs/op
 func BenchmarkNewJSONMaskInt(b *testing.B) {
 	var (
 		mask = NewJSONMask("fieldA")
@@ -162,7 +1272,7 @@ func BenchmarkNewJSONMaskInt(b *testing.B) {
 	}
 }
 
-// BenchmarkNewJSONMaskFloat64-16    	  353574	      3215 ns/op	    1785 B/op	      46 allocs/op
+// BenchmarkNewJSONMaskFloat64-16    	  149971	      8474 ns/op	    2016 B/op	      34 allocs/op
 func BenchmarkNewJSONMaskFloat64(b *testing.B) {
 	var (
 		mask = NewJSONMask("fieldA")
@@ -178,6 +1288,38 @@ func BenchmarkNewJSONMaskFloat64(b *testing.B) {
 	}
 }
 
+// BenchmarkMaskMatched-16    	   93555	     12303 ns/op	    2776 B/op	      62 allocs/op
+func BenchmarkMaskMatched(b *testing.B) {
+	var (
+		mask = NewJSONMask("fieldA")
+		json = `{"fieldA": "valueA", "metadata": {"fieldB": 1.234, "fieldC": "valueC", "list": [1, 2, 3]}}`
+	)
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = mask.Mask(json)
+	}
+}
+
+// BenchmarkMaskNotMatched-16    	  153096	      7704 ns/op	    2441 B/op	      48 allocs/op
+func BenchmarkMaskNotMatched(b *testing.B) {
+	var (
+		mask = NewJSONMask("fieldX")
+		json = `{"fieldA": "valueA", "metadata": {"fieldB": 1.234, "fieldC": "valueC", "list": [1, 2, 3]}}`
+	)
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = mask.Mask(json)
+	}
+}
+
 func testMaskRandomInt(val int) MaskIntFunc {
 	return func(path string, value int) (int, error) {
 		return val, nil
@@ -189,3 +1331,398 @@ func testMaskRandomFloat64(val float64) MaskFloat64Func {
 		return val, nil
 	}
 }
+
+func TestMaskIntRangeLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect string
+	}{
+		{name: "should label value of the first bound", value: `{"count":0}`, expect: `{"count":"0"}`},
+		{name: "should label value below the first bound", value: `{"count":-5}`, expect: `{"count":"0"}`},
+		{name: "should label value above the first bound", value: `{"count":1}`, expect: `{"count":"1-10"}`},
+		{name: "should label value of the middle bound", value: `{"count":10}`, expect: `{"count":"1-10"}`},
+		{name: "should label value above the middle bound", value: `{"count":11}`, expect: `{"count":"11-100"}`},
+		{name: "should label value of the last bound", value: `{"count":100}`, expect: `{"count":"11-100"}`},
+		{name: "should label value above the last bound", value: `{"count":101}`, expect: `{"count":"\u003e100"}`},
+		{name: "should keep non-integer value", value: `{"count":1.5}`, expect: `{"count":1.5}`},
+		{name: "should keep string value", value: `{"count":"7"}`, expect: `{"count":"7"}`},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("count")
+			mask.RegisterMaskValueFunc(MaskIntRangeLabel([]int{0, 10, 100}, []string{"0", "1-10", "11-100", ">100"}))
+
+			got, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskRandomIntSameWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int
+	}{
+		{name: "should keep width of zero", value: 0},
+		{name: "should keep width of single digit", value: 7},
+		{name: "should keep width of negative single digit", value: -3},
+		{name: "should keep width of two digits", value: 10},
+		{name: "should keep width of nine digits", value: 123456789},
+		{name: "should keep width and sign of negative number", value: -98765},
+		{name: "should keep width of max int", value: math.MaxInt},
+		{name: "should keep width of min int", value: math.MinInt},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			fn := MaskRandomIntSameWidth(42)
+
+			got, err := fn("/id", tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+
+			want := strconv.Itoa(tt.value)
+			if s := strconv.Itoa(got); len(s) != len(want) || (got < 0) != (tt.value < 0) {
+				t.Errorf("Process() got = %v, want width of %v", got, want)
+			}
+
+			if again, _ := fn("/id", tt.value); again != got {
+				t.Errorf("Process() got = %v, want %v", again, got)
+			}
+		})
+	}
+}
+
+func TestRegisterMaskNumberFunc(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:   "should pass integer token",
+			mask:   NewJSONMask("id", "big"),
+			value:  `{"id":42,"big":12345678901234567890123,"other":7}`,
+			expect: `{"big":-12345678901234567890123,"id":-42,"other":7}`,
+		},
+		{
+			name:   "should pass float token as is",
+			mask:   NewJSONMask("amount"),
+			value:  `{"amount":0.10}`,
+			expect: `{"amount":-0.10}`,
+		},
+		{
+			name:   "should pass exponent token",
+			mask:   NewJSONMask("e"),
+			value:  `{"e":[1e400,2.5E-3]}`,
+			expect: `{"e":[-1e400,-2.5E-3]}`,
+		},
+		{
+			name:   "should keep number skipped by the func",
+			mask:   NewJSONMask("id"),
+			value:  `{"id":-1.0}`,
+			expect: `{"id":-1.0}`,
+		},
+		{
+			name:   "should pass raw tokens with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("amount", "id"), WithCopyUnmatchedVerbatim()),
+			value:  `{"amount": 1.50e2, "id": 7, "other": 1.0}`,
+			expect: `{"amount": -1.50e2, "id": -7, "other": 1.0}`,
+		},
+		{
+			name:    "should fail for invalid token",
+			mask:    NewJSONMask("bad"),
+			value:   `{"bad":1}`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskInt64Func(func(_ string, v int64) (int64, error) { return v + 1, nil })
+			tt.mask.RegisterMaskNumberFunc(func(path, raw string) (string, error) {
+				switch {
+				case strings.HasPrefix(raw, "-"):
+					return raw, ErrSkip
+				case path == "/bad":
+					return "si" + raw, nil
+				default:
+					return "-" + raw, nil
+				}
+			})
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidNumberToken) {
+					t.Errorf("Process() error = %v, want %v", err, ErrInvalidNumberToken)
+				}
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskProportionalNoise(t *testing.T) {
+	tests := []struct {
+		name  string
+		pct   float64
+		value float64
+	}{
+		{name: "should keep zero", pct: 0.1, value: 0},
+		{name: "should add noise to duration", pct: 0.1, value: 250},
+		{name: "should add noise to negative value", pct: 0.2, value: -12.5},
+		{name: "should add noise to small value", pct: 0.05, value: 0.003},
+		{name: "should keep value without noise", pct: 0, value: 42},
+		{name: "should use absolute pct", pct: -0.1, value: 1000},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			fn := MaskProportionalNoise(tt.pct, 42)
+
+			got, err := fn("/duration", tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+
+			lo, hi := tt.value*(1-math.Abs(tt.pct)), tt.value*(1+math.Abs(tt.pct))
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if got < lo || got > hi {
+				t.Errorf("Process() got = %v, want within [%v, %v]", got, lo, hi)
+			}
+
+			if again, _ := MaskProportionalNoise(tt.pct, 42)("/duration", tt.value); again != got {
+				t.Errorf("Process() got = %v, want %v", again, got)
+			}
+		})
+	}
+}
+
+func TestMaskProportionalNoiseSpread(t *testing.T) {
+	fn := MaskProportionalNoise(0.1, 7)
+
+	seen := make(map[float64]struct{})
+	for i := 1; i <= 100; i++ {
+		got, _ := fn("/duration", float64(i))
+		if got < float64(i)*0.9 || got > float64(i)*1.1 {
+			t.Fatalf("Process() got = %v, want within 10%% of %v", got, i)
+		}
+		seen[got/float64(i)] = struct{}{}
+	}
+
+	if len(seen) < 90 {
+		t.Errorf("Process() got %d distinct factors, want at least 90", len(seen))
+	}
+}
+
+func TestMaskIntRangeLabelInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds []int
+		labels []string
+	}{
+		{name: "should reject missing label", bounds: []int{0, 10}, labels: []string{"0", ">0"}},
+		{name: "should reject extra label", bounds: []int{0}, labels: []string{"0", "1", "2"}},
+		{name: "should reject unsorted bounds", bounds: []int{10, 0}, labels: []string{"a", "b", "c"}},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			_, err := MaskIntRangeLabel(tt.bounds, tt.labels)("/count", 1.0)
+			if !errors.Is(err, ErrInvalidRange) {
+				t.Errorf("Process() error = %v, want %v", err, ErrInvalidRange)
+			}
+		})
+	}
+}
+
+func TestRegisterPathValueRegex(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect string
+	}{
+		{
+			name:   "should replace matches at the path",
+			value:  `{"logs":{"message":"sent to bob@x.io and al@y.io"}}`,
+			expect: `{"logs":{"message":"sent to [email] and [email]"}}`,
+		},
+		{
+			name:   "should keep value without matches at the path",
+			value:  `{"logs":{"message":"sent"}}`,
+			expect: `{"logs":{"message":"sent"}}`,
+		},
+		{
+			name:   "should keep matches at other paths",
+			value:  `{"logs":{"message":"ok","to":"bob@x.io"},"message":"bob@x.io"}`,
+			expect: `{"logs":{"message":"ok","to":"bob@x.io"},"message":"bob@x.io"}`,
+		},
+		{
+			name:   "should keep non-string value at the path",
+			value:  `{"logs":{"message":12}}`,
+			expect: `{"logs":{"message":12}}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask()
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			mask.RegisterMaskIntFunc(MaskRandomInt())
+			mask.RegisterPathValueRegex("/logs/message", regexp.MustCompile(`[\w.]+@[\w.]+`), "[email]")
+
+			got, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterArrayFunc(t *testing.T) {
+	mean := func(_ string, elems []any) ([]any, error) {
+		var sum float64
+		for _, elem := range elems {
+			n, ok := elem.(json.Number)
+			if !ok {
+				return nil, ErrSkip
+			}
+
+			f, err := n.Float64()
+			if err != nil {
+				return nil, err
+			}
+			sum += f
+		}
+
+		res := make([]any, len(elems))
+		for i := range res {
+			res[i] = sum / float64(len(elems))
+		}
+		return res, nil
+	}
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		path   string
+		value  string
+		expect string
+	}{
+		{
+			name:   "should replace numbers with their mean",
+			mask:   NewJSONMask("ssn"),
+			path:   "/salaries",
+			value:  `{"salaries":[10,20,60],"other":[1,2]}`,
+			expect: `{"other":[1,2],"salaries":[30,30,30]}`,
+		},
+		{
+			name:   "should mask items as usual when func skips the array",
+			mask:   NewJSONMask("salaries"),
+			path:   "/salaries",
+			value:  `{"salaries":["a",1]}`,
+			expect: `{"salaries":["*",1]}`,
+		},
+		{
+			name:   "should replace numbers of root array",
+			mask:   NewJSONMask(),
+			path:   "/",
+			value:  `[1,2]`,
+			expect: `[1.5,1.5]`,
+		},
+		{
+			name:   "should replace numbers with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithCopyUnmatchedVerbatim()),
+			path:   "/users[0]/scores",
+			value:  `{"users": [{"scores": [1, 3]}], "id": 7}`,
+			expect: `{"users": [{"scores": [2,2]}], "id": 7}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterArrayFunc(tt.path, mean)
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterMaskFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(m *JsonMask)
+		expect   string
+	}{
+		{
+			name: "should dispatch string func",
+			register: func(m *JsonMask) {
+				RegisterMaskFunc(m, func(_ string, v string) (string, error) { return "[" + v + "]", nil })
+			},
+			expect: `{"a":"[x]","b":1,"c":1.5}`,
+		},
+		{
+			name: "should dispatch float64 func",
+			register: func(m *JsonMask) {
+				RegisterMaskFunc(m, func(_ string, v float64) (float64, error) { return v * 10, nil })
+			},
+			expect: `{"a":"x","b":10,"c":15}`,
+		},
+		{
+			name: "should dispatch int64 func to integers only",
+			register: func(m *JsonMask) {
+				RegisterMaskFunc(m, func(_ string, v int64) (int64, error) { return v + 1, nil })
+			},
+			expect: `{"a":"x","b":2,"c":1.5}`,
+		},
+		{
+			name: "should dispatch int func to integers only",
+			register: func(m *JsonMask) {
+				RegisterMaskFunc(m, func(_ string, v int) (int, error) { return v + 2, nil })
+			},
+			expect: `{"a":"x","b":3,"c":1.5}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("a", "b", "c")
+			tt.register(mask)
+
+			got, err := mask.Mask(`{"a":"x","b":1,"c":1.5}`)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
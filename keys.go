@@ -0,0 +1,141 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maskKey method for masking key k of the member by xpath fk with the func of RegisterMaskKeyFunc,
+// keys out of WithKeyMaskScope are returned as is
+func (j *JsonMask) maskKey(st *state, k, fk string) (string, error) {
+	if j.maskKeyFunc == nil || !j.inKeyScope(fk) {
+		return k, nil
+	}
+
+	res, err := j.maskKeyFunc(fk, k)
+	if errors.Is(err, ErrSkip) {
+		return k, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fk, err)
+	}
+
+	if res != k {
+		st.changed = true
+	}
+
+	return res, nil
+}
+
+// inKeyScope method for checking that the member by xpath fk is under one of WithKeyMaskScope prefixes
+func (j *JsonMask) inKeyScope(fk string) bool {
+	if len(j.keyScopes) == 0 {
+		return true
+	}
+
+	path := j.pathKey(fk)
+	for _, prefix := range j.keyScopes {
+		if prefix == pathKey {
+			return true
+		}
+
+		if rest, ok := strings.CutPrefix(path, prefix); ok && (strings.HasPrefix(rest, pathKey) || strings.HasPrefix(rest, "[")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyRename is a member of the object renamed by RegisterMaskKeyFunc
+type keyRename struct {
+	key, name string
+}
+
+// maskKeys method for masking keys of the object of the frame after its members are masked, the members are
+// renamed at once so a chain of renames (a -> b, b -> c) keeps all values. Returns ErrKeyCollision if a masked key
+// equals another key of the object
+func (j *JsonMask) maskKeys(st *state, fr *frame) error {
+	if j.maskKeyFunc == nil || fr.wrapper {
+		return nil
+	}
+
+	switch {
+	case fr.m != nil:
+		var renames []keyRename
+		for _, k := range fr.keys {
+			if _, ok := fr.m[k]; !ok {
+				continue
+			}
+
+			name, err := j.maskKey(st, k, fr.pk+pathKey+k)
+			if err != nil {
+				return err
+			}
+
+			if name != k {
+				renames = append(renames, keyRename{key: k, name: name})
+			}
+		}
+
+		if len(renames) == 0 {
+			return nil
+		}
+
+		fr.renamed = true
+
+		// renames are applied in order of the new keys, so the reported collision doesn't depend on the map order
+		sort.Slice(renames, func(a, b int) bool { return renames[a].name < renames[b].name })
+
+		// renamed members are taken out of the object before adding them back, so renamed keys don't replace
+		// the original ones renamed too
+		vals := make([]any, len(renames))
+		for i, r := range renames {
+			vals[i] = fr.m[r.key]
+		}
+		for _, r := range renames {
+			delete(fr.m, r.key)
+		}
+
+		for i, r := range renames {
+			if _, ok := fr.m[r.name]; ok {
+				return fmt.Errorf("%s%s%s: %w", fr.pk, pathKey, r.name, ErrKeyCollision)
+			}
+			fr.m[r.name] = vals[i]
+		}
+	case fr.o != nil:
+		names := make(keyNames, len(fr.o))
+		for i, m := range fr.o {
+			name, err := j.maskKey(st, m.key, fr.pk+pathKey+m.key)
+			if err != nil {
+				return err
+			}
+
+			if err := names.add(fr.pk, name, name != m.key); err != nil {
+				return err
+			}
+			fr.o[i].key = name
+			fr.renamed = fr.renamed || name != m.key
+		}
+	}
+
+	return nil
+}
+
+// keyNames are masked keys of the object members reporting whether the key is renamed by RegisterMaskKeyFunc,
+// duplicate keys of the document are kept unless one of them is renamed
+type keyNames map[string]bool
+
+// add method for adding masked key name of the member of the object by xpath pk,
+// returns ErrKeyCollision if the key collides with another one and any of them is renamed
+func (n keyNames) add(pk, name string, renamed bool) error {
+	if prev, ok := n[name]; ok && (prev || renamed) {
+		return fmt.Errorf("%s%s%s: %w", pk, pathKey, name, ErrKeyCollision)
+	}
+
+	n[name] = n[name] || renamed
+	return nil
+}
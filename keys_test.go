@@ -0,0 +1,160 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRegisterMaskKeyFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask keys everywhere without scope",
+			mask:   NewJSONMask("ssn"),
+			value:  `{"ssn":"123","user":{"ssn":"45"}}`,
+			expect: `{"SSN":"***","USER":{"SSN":"**"}}`,
+		},
+		{
+			name:   "should mask keys only under scope prefixes",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithKeyMaskScope("/emails")),
+			value:  `{"ssn":"123","emails":{"bob@x.io":{"ssn":"45"}},"user":{"ssn":"6"}}`,
+			expect: `{"emails":{"BOB@X.IO":{"SSN":"**"}},"ssn":"***","user":{"ssn":"*"}}`,
+		},
+		{
+			name:   "should mask keys of objects in scoped array",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithKeyMaskScope("/users")),
+			value:  `{"users":[{"a":"1"}],"usersx":{"a":"1"}}`,
+			expect: `{"users":[{"A":"1"}],"usersx":{"a":"1"}}`,
+		},
+		{
+			name:   "should mask keys of ordered objects in scope",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithKeyMaskScope("/user"), WithOrderedKeys()),
+			value:  `{"user":{"ssn":"12","id":1},"id":2}`,
+			expect: `{"user":{"SSN":"**","ID":1},"id":2}`,
+		},
+		{
+			name:   "should mask keys in scope with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithKeyMaskScope("/user"), WithCopyUnmatchedVerbatim()),
+			value:  `{"ssn": "12", "user": {"ssn": "3", "id": 1}}`,
+			expect: `{"ssn": "**", "user": {"SSN": "*", "ID": 1}}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskKeyFunc(func(_, key string) (string, error) { return strings.ToUpper(key), nil })
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterMaskKeyFuncCollision(t *testing.T) {
+	rename := func(_, key string) (string, error) {
+		switch key {
+		case "a":
+			return "b", nil
+		case "b":
+			return "c", nil
+		}
+		return "", ErrSkip
+	}
+
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		fn      MaskStringFunc
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:    "should return error on keys masked into the same key",
+			mask:    NewJSONMask(),
+			fn:      MaskFixedString("k"),
+			value:   `{"a":1,"b":2,"c":3}`,
+			wantErr: true,
+		},
+		{
+			name:    "should return error on keys masked into the same key with verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithCopyUnmatchedVerbatim()),
+			fn:      MaskFixedString("k"),
+			value:   `{"a": 1, "b": 2, "c": 3}`,
+			wantErr: true,
+		},
+		{
+			name:    "should return error on keys masked into the same key of ordered object",
+			mask:    NewJSONMaskWithOptions(WithOrderedKeys()),
+			fn:      MaskFixedString("k"),
+			value:   `{"a":1,"b":2}`,
+			wantErr: true,
+		},
+		{
+			name:    "should return error on masked key equal to kept key",
+			mask:    NewJSONMask(),
+			fn:      rename,
+			value:   `{"b":1,"c":2}`,
+			wantErr: true,
+		},
+		{
+			name:    "should return error on masked key equal to kept key with verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithCopyUnmatchedVerbatim()),
+			fn:      rename,
+			value:   `{"c": 2, "b": 1}`,
+			wantErr: true,
+		},
+		{
+			name:   "should keep all values of chain rename",
+			mask:   NewJSONMask(),
+			fn:     rename,
+			value:  `{"a":1,"b":2,"d":3}`,
+			expect: `{"b":1,"c":2,"d":3}`,
+		},
+		{
+			name:   "should keep all values of chain rename with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithCopyUnmatchedVerbatim()),
+			fn:     rename,
+			value:  `{"a": 1, "b": 2, "d": 3}`,
+			expect: `{"b": 1, "c": 2, "d": 3}`,
+		},
+		{
+			name:   "should keep duplicate keys of the document with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithCopyUnmatchedVerbatim()),
+			fn:     rename,
+			value:  `{"d": 1, "d": 2}`,
+			expect: `{"d": 1, "d": 2}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskKeyFunc(tt.fn)
+
+			for n := 0; n < 10; n++ {
+				got, err := tt.mask.Mask(tt.value)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if err != nil && !errors.Is(err, ErrKeyCollision) {
+					t.Errorf("Process() error = %v, want %v", err, ErrKeyCollision)
+				}
+				if got != tt.expect {
+					t.Errorf("Process() got = %v, want %v", got, tt.expect)
+				}
+			}
+		})
+	}
+}
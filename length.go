@@ -0,0 +1,34 @@
+package jsonmask
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// LongValuePolicy is a behavior for matched string values longer than WithMaxValueLength
+type LongValuePolicy int
+
+const (
+	// LongValueTruncate passes the first n bytes of the value (cut on a rune boundary) to the mask func
+	LongValueTruncate LongValuePolicy = iota
+	// LongValueError makes masking fail with ErrValueTooLong
+	LongValueError
+)
+
+// limitLength method for applying WithMaxValueLength to the string value by xpath fk before masking
+func (j *JsonMask) limitLength(fk, v string) (string, error) {
+	if j.maxValueLength <= 0 || len(v) <= j.maxValueLength {
+		return v, nil
+	}
+
+	if j.longValuePolicy == LongValueError {
+		return "", fmt.Errorf("%s: %w: %d bytes, limit %d", rootPath(fk), ErrValueTooLong, len(v), j.maxValueLength)
+	}
+
+	n := j.maxValueLength
+	for n > 0 && !utf8.RuneStart(v[n]) {
+		n--
+	}
+
+	return v[:n], nil
+}
@@ -0,0 +1,57 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWithMaxValueLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr error
+	}{
+		{
+			name:   "should truncate long value before masking",
+			mask:   NewJSONMaskWithOptions(WithFields("a", "b"), WithMaxValueLength(4, LongValueTruncate)),
+			value:  `{"a":"` + strings.Repeat("x", 1000) + `","b":"xyz"}`,
+			expect: `{"a":"****","b":"***"}`,
+		},
+		{
+			name:   "should truncate long value on rune boundary",
+			mask:   NewJSONMaskWithOptions(WithFields("a"), WithMaxValueLength(4, LongValueTruncate)),
+			value:  `{"a":"xyzäö"}`,
+			expect: `{"a":"***"}`,
+		},
+		{
+			name:   "should keep long unmatched value",
+			mask:   NewJSONMaskWithOptions(WithFields("a"), WithMaxValueLength(2, LongValueError)),
+			value:  `{"a":"xy","b":"` + strings.Repeat("x", 10) + `"}`,
+			expect: `{"a":"**","b":"` + strings.Repeat("x", 10) + `"}`,
+		},
+		{
+			name:    "should return error on long value",
+			mask:    NewJSONMaskWithOptions(WithFields("a"), WithMaxValueLength(4, LongValueError)),
+			value:   `{"a":"xyzab"}`,
+			wantErr: ErrValueTooLong,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
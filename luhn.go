@@ -0,0 +1,109 @@
+package jsonmask
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strings"
+)
+
+// card numbers have from 12 to 19 digits
+const (
+	minCardDigits = 12
+	maxCardDigits = 19
+)
+
+// MaskLuhnSurrogate replaces a valid card number (Luhn check, 12-19 digits, optionally separated by spaces
+// or dashes) with a random number of the same length which passes the Luhn check too, separators stay in place.
+// keepPrefix is the number of leading digits kept as is, 6 keeps the BIN. The surrogate is derived from
+// the seed and the original number, so the same card gets the same surrogate. keepPrefix is capped so that
+// at least one digit besides the check digit is replaced and the surrogate always differs from the number.
+// Other values are left unchanged
+func MaskLuhnSurrogate(seed int64, keepPrefix ...int) MaskStringFunc {
+	keep := 0
+	if len(keepPrefix) > 0 {
+		keep = keepPrefix[0]
+	}
+
+	return func(_, val string) (string, error) {
+		digits, ok := cardDigits(val)
+		if !ok {
+			return val, ErrSkip
+		}
+
+		h := fnv.New64a()
+		h.Write(digits)
+		rnd := rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+
+		n := len(digits)
+		kept := keep
+		if kept > n-2 {
+			kept = n - 2
+		}
+
+		surrogate := make([]byte, n)
+		for i := 0; i < n-1; i++ {
+			if i < kept {
+				surrogate[i] = digits[i]
+				continue
+			}
+			surrogate[i] = byte('0' + rnd.Intn(10))
+		}
+
+		// the payload equal to the original one gives the same number, so its last digit is replaced by another
+		if string(surrogate[:n-1]) == string(digits[:n-1]) {
+			surrogate[n-2] = byte('0' + (int(surrogate[n-2]-'0')+1+rnd.Intn(9))%10)
+		}
+		surrogate[n-1] = luhnCheckDigit(surrogate[:n-1])
+
+		var b strings.Builder
+		i := 0
+		for _, r := range val {
+			if r >= '0' && r <= '9' {
+				b.WriteByte(surrogate[i])
+				i++
+				continue
+			}
+			b.WriteRune(r)
+		}
+
+		return b.String(), nil
+	}
+}
+
+// cardDigits returns digits of the card number, the value must have only digits, spaces and dashes
+// and pass the Luhn check
+func cardDigits(val string) ([]byte, bool) {
+	digits := make([]byte, 0, maxCardDigits)
+	for i := 0; i < len(val); i++ {
+		switch c := val[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == ' ' || c == '-':
+		default:
+			return nil, false
+		}
+	}
+
+	if len(digits) < minCardDigits || len(digits) > maxCardDigits {
+		return nil, false
+	}
+
+	return digits, luhnCheckDigit(digits[:len(digits)-1]) == digits[len(digits)-1]
+}
+
+// luhnCheckDigit returns the check digit making the payload a valid Luhn number
+func luhnCheckDigit(payload []byte) byte {
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		if (len(payload)-i)%2 == 1 { // doubled when the check digit is appended
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,87 @@
+package jsonmask
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMaskLuhnSurrogate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		keep    int
+		changed bool
+	}{
+		{name: "should replace 16 digit card", value: "4111111111111111", changed: true},
+		{name: "should replace card with separators", value: "5500 0000 0000 0004", changed: true},
+		{name: "should replace 15 digit card keeping BIN", value: "3782-822463-10005", keep: 6, changed: true},
+		{name: "should replace 19 digit card", value: "6011000990139424009", changed: true},
+		{name: "should replace digits beyond too long prefix", value: "4111 1111 1111 1111", keep: 30, changed: true},
+		{name: "should replace all digits with negative prefix", value: "4111111111111111", keep: -1, changed: true},
+		{name: "should keep number failing the Luhn check", value: "4111111111111112", changed: false},
+		{name: "should keep too short number", value: "42", changed: false},
+		{name: "should keep text", value: "4111-1111-1111-111x", changed: false},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := MaskLuhnSurrogate(42, tt.keep)("/card", tt.value)
+			if tt.changed != (err == nil) {
+				t.Errorf("Process() error = %v, wantErr %v", err, !tt.changed)
+				return
+			}
+			if !tt.changed {
+				if got != tt.value {
+					t.Errorf("Process() got = %v, want %v", got, tt.value)
+				}
+				return
+			}
+
+			if len(got) != len(tt.value) || got == tt.value {
+				t.Errorf("Process() got = %v, want other card of length %d", got, len(tt.value))
+			}
+			if _, ok := cardDigits(got); !ok {
+				t.Errorf("Process() got = %v, want Luhn-valid number", got)
+			}
+			if strings.Map(testKeepSeparators, got) != strings.Map(testKeepSeparators, tt.value) {
+				t.Errorf("Process() got = %v, want separators of %v", got, tt.value)
+			}
+
+			digits := strings.ReplaceAll(strings.ReplaceAll(tt.value, " ", ""), "-", "")
+			keep := digits[:min(max(tt.keep, 0), len(digits)-2)]
+			if !strings.HasPrefix(strings.ReplaceAll(strings.ReplaceAll(got, " ", ""), "-", ""), keep) {
+				t.Errorf("Process() got = %v, want prefix %v", got, keep)
+			}
+
+			again, _ := MaskLuhnSurrogate(42, tt.keep)("/card", tt.value)
+			if again != got {
+				t.Errorf("Process() got = %v, want the same surrogate %v", again, got)
+			}
+		})
+	}
+}
+
+// testKeepSeparators maps digits to a placeholder to compare separator positions
+func testKeepSeparators(r rune) rune {
+	if r >= '0' && r <= '9' {
+		return '0'
+	}
+	return r
+}
+
+func TestMaskLuhnSurrogateNeverOriginal(t *testing.T) {
+	const value = "4111111111111111"
+
+	for seed := int64(0); seed < 1000; seed++ {
+		got, err := MaskLuhnSurrogate(seed, len(value))("/card", value)
+		if err != nil {
+			t.Fatalf("Process() error = %v, wantErr %v", err, false)
+		}
+		if got == value || got[:len(value)-2] != value[:len(value)-2] {
+			t.Fatalf("Process() got = %v for seed %d, want other card with prefix %v", got, seed, value[:len(value)-2])
+		}
+		if _, ok := cardDigits(got); !ok {
+			t.Fatalf("Process() got = %v for seed %d, want Luhn-valid number", got, seed)
+		}
+	}
+}
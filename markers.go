@@ -0,0 +1,20 @@
+package jsonmask
+
+// markMasked method for wrapping the masked value of field k by xpath fk into the object of WithMaskMarkers,
+// xpath markers take precedence over global ones
+func (j *JsonMask) markMasked(k, fk string, res any) any {
+	if len(j.markers) == 0 && len(j.pathMarkers) == 0 {
+		return res
+	}
+
+	kind, ok := j.pathMarkers[j.pathKey(fk)]
+	if !ok {
+		kind, ok = j.markers[j.fold(k)]
+	}
+
+	if !ok {
+		return res
+	}
+
+	return map[string]any{"masked": true, "kind": kind, "value": res}
+}
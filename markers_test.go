@@ -0,0 +1,54 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWithMaskMarkers(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should wrap masked values of marker fields",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn", "phone", "email"), WithMaskMarkers("hash", "ssn", "/user/phones")),
+			value:  `{"ssn":"123","email":"a@b.c","name":"bob","user":{"phones":["12"]}}`,
+			expect: `{"email":"*****","name":"bob","ssn":{"kind":"hash","masked":true,"value":"***"},"user":{"phones":["12"]}}`,
+		},
+		{
+			name:   "should wrap masked items of marker field array",
+			mask:   NewJSONMaskWithOptions(WithFields("phones"), WithMaskMarkers("fill", "/user/phones[0]"), WithMaskMarkers("hash", "phones")),
+			value:  `{"user":{"phones":["12","3"]}}`,
+			expect: `{"user":{"phones":[{"kind":"fill","masked":true,"value":"**"},{"kind":"hash","masked":true,"value":"*"}]}}`,
+		},
+		{
+			name:   "should keep unmatched marker fields untouched",
+			mask:   NewJSONMaskWithOptions(WithFields("email"), WithMaskMarkers("hash", "ssn")),
+			value:  `{"ssn":"123","email":"a"}`,
+			expect: `{"email":"*","ssn":"123"}`,
+		},
+		{
+			name:   "should wrap masked values with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithMaskMarkers("fill", "ssn"), WithCopyUnmatchedVerbatim()),
+			value:  `{"name": "bob", "ssn": "12"}`,
+			expect: `{"name": "bob", "ssn": {"kind":"fill","masked":true,"value":"**"}}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
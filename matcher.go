@@ -0,0 +1,125 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// list of kinds returned by Matcher to choose the mask func of the matched leaf value
+const (
+	// MatchAuto masks the value by its type as if the field was matched by a global or xpath field
+	MatchAuto = ""
+	// MatchString masks the value with MaskStringFunc, numbers are passed in the decimal form
+	MatchString = "string"
+	// MatchInt masks integer numbers with MaskInt64Func/MaskIntFunc
+	MatchInt = "int"
+	// MatchFloat64 masks numbers with MaskFloat64Func
+	MatchFloat64 = "float64"
+	// MatchValue masks the value of any type with MaskValueFunc
+	MatchValue = "value"
+)
+
+// Matcher receives every leaf value (string, number, bool, null) by the xpath and decides whether it's masked,
+// the kind is one of Match* kinds choosing the mask func. Numbers are json.Number for decoded JSON and float64
+// for MaskAny values
+type Matcher func(path string, value any) (apply bool, kind string)
+
+// RegisterMatcher method for adding Matcher to JsonMask, it's called for leaf values before the others rules
+// and leaves not applied by it are masked by the others rules as usual
+func (j *JsonMask) RegisterMatcher(fn Matcher) {
+	j.matcher = fn
+}
+
+// match method for calling Matcher registered by RegisterMatcher for the leaf value by xpath fk
+func (j *JsonMask) match(fk string, val any) (bool, string) {
+	if j.matcher == nil || isContainer(val) {
+		return false, ""
+	}
+
+	return j.matcher(fk, val)
+}
+
+// maskKind method for masking leaf value of field k by xpath fk with the func of the kind returned by Matcher
+func (j *JsonMask) maskKind(st *state, k, fk string, val any, kind string) (any, error) {
+	if kind == MatchValue {
+		fn := j.valueFunc(fk)
+		if fn == nil {
+			return val, nil
+		}
+
+		return j.maskValueKind(st, fk, val, fn)
+	}
+
+	if s, ok := val.(string); ok && kind == MatchString {
+		return j.maskString(st, k, fk, s, false)
+	}
+
+	var (
+		f   float64
+		num json.Number
+	)
+	switch v := val.(type) {
+	case float64:
+		f = v
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil && kind != MatchString { // out of float64 range numbers are masked only as strings
+			return nil, fmt.Errorf("%s: %w: %s", fk, ErrNumberRange, v)
+		}
+		f, num = n, v
+	default:
+		return nil, fmt.Errorf("%s: %w: %q for %T", fk, ErrMatcherKind, kind, val)
+	}
+
+	changed := st.changed
+	st.changed = false
+
+	var (
+		res any
+		err error
+	)
+	switch {
+	case kind == MatchString:
+		res, err = j.maskString(st, k, fk, numberString(f, num), false)
+	case kind == MatchInt && f == math.Trunc(f):
+		res, err = j.maskNumber(st, k, fk, f, num, false)
+	case kind == MatchFloat64:
+		res, err = j.maskNumberFloat64(st, fk, f)
+	default:
+		err = fmt.Errorf("%s: %w: %q for %v", fk, ErrMatcherKind, kind, val)
+	}
+
+	// unmasked number keeps the original type and representation
+	if err == nil && !st.changed {
+		res = val
+	}
+
+	st.changed = st.changed || changed
+	return res, err
+}
+
+// maskValueKind method for masking leaf value with MaskValueFunc for MatchValue kind, json.Number is passed
+// as float64 like for the fields matched by the others rules and keeps its representation if it isn't masked
+func (j *JsonMask) maskValueKind(st *state, fk string, val any, fn MaskValueFunc) (any, error) {
+	num, ok := val.(json.Number)
+	if !ok {
+		return j.maskLeaf(st, fk, val, fn)
+	}
+
+	f, err := num.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", fk, ErrNumberRange, num)
+	}
+
+	res, err := j.maskLeaf(st, fk, f, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	if r, ok := res.(float64); ok && r == f {
+		return num, nil
+	}
+
+	return res, nil
+}
@@ -0,0 +1,197 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRegisterMatcher(t *testing.T) {
+	// matcher masking emails by value, card numbers as strings and amounts as floats
+	matcher := func(path string, value any) (bool, string) {
+		switch v := value.(type) {
+		case string:
+			return strings.Contains(v, "@"), MatchAuto
+		case json.Number, float64:
+			switch {
+			case strings.HasSuffix(path, "/card"):
+				return true, MatchString
+			case strings.HasSuffix(path, "/amount"):
+				return true, MatchFloat64
+			case strings.HasSuffix(path, "/id"):
+				return true, MatchInt
+			}
+		}
+		return false, ""
+	}
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask matched strings by value",
+			mask:   NewJSONMask(),
+			value:  `{"contact":"bob@example.com","name":"bob","list":["a@b","c"]}`,
+			expect: `{"contact":"***************","list":["***","c"],"name":"bob"}`,
+		},
+		{
+			name:   "should route matched numbers by kind",
+			mask:   NewJSONMask(),
+			value:  `{"card":4111,"amount":10.5,"id":7,"count":3}`,
+			expect: `{"amount":21,"card":"****","count":3,"id":8}`,
+		},
+		{
+			name:   "should keep the others rules for leaves not applied by matcher",
+			mask:   NewJSONMask("name"),
+			value:  `{"name":"bob","count":3}`,
+			expect: `{"count":3,"name":"***"}`,
+		},
+		{
+			name:   "should mask matched leaves with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithCopyUnmatchedVerbatim()),
+			value:  `{"contact": "a@b", "amount": 10.5, "card": 4111}`,
+			expect: `{"contact": "***", "amount": 21, "card": "****"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMatcher(matcher)
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskFloat64Func(func(_ string, v float64) (float64, error) { return v * 2, nil })
+			tt.mask.RegisterMaskInt64Func(func(_ string, v int64) (int64, error) { return v + 1, nil })
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterMatcherValue(t *testing.T) {
+	matcher := func(path string, _ any) (bool, string) {
+		return path == "/n" || path == "/m" || path == "/big", MatchValue
+	}
+
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr error
+	}{
+		{
+			name:   "should pass numbers to MaskValueFunc as float64",
+			mask:   NewJSONMask(),
+			value:  `{"n":5,"m":1.50,"k":7}`,
+			expect: `{"k":7,"m":1.50,"n":"\u003c=10"}`,
+		},
+		{
+			name:   "should pass numbers to MaskValueFunc as float64 with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithCopyUnmatchedVerbatim()),
+			value:  `{"n": 5, "m": 1.50, "k": 7}`,
+			expect: `{"n": "\u003c=10", "m": 1.50, "k": 7}`,
+		},
+		{
+			name:    "should return error for number out of float64 range",
+			mask:    NewJSONMask(),
+			value:   `{"big":1e400}`,
+			wantErr: ErrNumberRange,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMatcher(matcher)
+			tt.mask.RegisterMaskValueFunc(MaskIntRangeLabel([]int{10}, []string{"<=10", ">10"}))
+
+			got, err := tt.mask.Mask(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterMatcherInvalidKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		value   string
+		wantErr error
+	}{
+		{
+			name:    "should fail for unknown kind",
+			kind:    "date",
+			value:   `{"a":"x"}`,
+			wantErr: ErrMatcherKind,
+		},
+		{
+			name:    "should fail for int kind of string",
+			kind:    MatchInt,
+			value:   `{"a":"x"}`,
+			wantErr: ErrMatcherKind,
+		},
+		{
+			name:    "should fail for int kind of fraction",
+			kind:    MatchInt,
+			value:   `{"a":1.5}`,
+			wantErr: ErrMatcherKind,
+		},
+		{
+			name:    "should fail for number out of float range",
+			kind:    MatchFloat64,
+			value:   `{"a":1e400}`,
+			wantErr: ErrNumberRange,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask()
+			mask.RegisterMatcher(func(string, any) (bool, string) { return true, tt.kind })
+
+			if _, err := mask.Mask(tt.value); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterMatcherCalls(t *testing.T) {
+	var paths []string
+
+	mask := NewJSONMask()
+	mask.RegisterMatcher(func(path string, _ any) (bool, string) {
+		paths = append(paths, path)
+		return true, MatchAuto
+	})
+	mask.RegisterMaskInt64Func(func(_ string, v int64) (int64, error) { return v + 1, nil })
+
+	got, err := mask.Mask(`{"a":1,"b":[2]}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v, wantErr %v", err, false)
+	}
+	if expect := `{"a":2,"b":[3]}`; got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+
+	// the matcher is called once for every leaf
+	sort.Strings(paths)
+	if expect := []string{"/a", "/b[0]"}; !reflect.DeepEqual(paths, expect) {
+		t.Errorf("Process() calls = %v, want %v", paths, expect)
+	}
+}
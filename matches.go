@@ -0,0 +1,77 @@
+package jsonmask
+
+import "strings"
+
+// matchStep is a value on the path to a leaf, k is the field key (inherited by array items) and fk is its xpath
+type matchStep struct {
+	k, fk string
+	index bool
+}
+
+// Matches method for checking whether a leaf value by the xpath ("/user/emails[0]", "/" for the root value,
+// "/[0]/key" for a root array) would be masked by global, xpath, prefix, suffix, key glob and scoped fields,
+// including fields beneath a matched global field. Rules depending on values like WithRedactValues
+// and WithDiscriminatorRule aren't taken into account
+func (j *JsonMask) Matches(path string) bool {
+	st := &state{}
+	if strings.HasPrefix(path, "[") {
+		path = pathKey + path
+	}
+
+	steps := matchSteps(path)
+	if len(steps) == 0 {
+		return false
+	}
+
+	ignoreGlobal := true
+	for i, step := range steps[:len(steps)-1] {
+		st.depth = i + 1
+		// an object passes the global field match to its values, an array passes it to its items as is
+		if !steps[i+1].index {
+			ignoreGlobal = !(!ignoreGlobal || j.isGlobalField(st, step.k, step.fk))
+		}
+	}
+
+	last := steps[len(steps)-1]
+	st.depth = len(steps)
+	return j.isStringMatched(st, last.k, last.fk, ignoreGlobal)
+}
+
+// matchSteps splits the xpath into the values on the path the traversal visits, nil for a malformed xpath
+func matchSteps(path string) []matchStep {
+	if path == pathKey {
+		return []matchStep{{k: "", fk: pathKey}}
+	}
+
+	if validatePath(path) != nil {
+		return nil
+	}
+
+	var (
+		steps []matchStep
+		pk    string
+	)
+	for i, segment := range strings.Split(path[1:], pathKey) {
+		key, indices, _ := strings.Cut(segment, "[")
+
+		fk := pk + pathKey + key
+		if i == 0 && key == "" { // root array
+			fk = pathKey
+		} else {
+			steps = append(steps, matchStep{k: key, fk: fk})
+		}
+
+		for _, idx := range strings.Split(indices, "[") {
+			if idx == "" {
+				continue
+			}
+
+			fk += "[" + idx
+			steps = append(steps, matchStep{k: key, fk: fk, index: true})
+		}
+
+		pk = fk
+	}
+
+	return steps
+}
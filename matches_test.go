@@ -0,0 +1,51 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		path   string
+		expect bool
+	}{
+		{name: "should match global field", mask: NewJSONMask("ssn"), path: "/user/ssn", expect: true},
+		{name: "should match value beneath global field", mask: NewJSONMask("user"), path: "/user/emails[1]", expect: true},
+		{name: "should match items of global field array", mask: NewJSONMask("emails"), path: "/user/emails[0][1]", expect: true},
+		{name: "should not match other field", mask: NewJSONMask("ssn"), path: "/user/name", expect: false},
+		{name: "should match xpath field", mask: NewJSONMask("/user/emails[0]"), path: "/user/emails[0]", expect: true},
+		{name: "should not match other index of xpath field", mask: NewJSONMask("/user/emails[0]"), path: "/user/emails[1]", expect: false},
+		{name: "should not match value beneath xpath field", mask: NewJSONMask("/user"), path: "/user/name", expect: false},
+		{name: "should match xpath field ignoring indices", mask: NewJSONMaskWithOptions(WithPathFields("/list/id"), WithIndexAgnosticPaths()), path: "/list[3]/id", expect: true},
+		{name: "should match prefix field", mask: NewJSONMaskWithOptions(WithPrefixFields("secret_")), path: "/a/secret_key", expect: true},
+		{name: "should match suffix field", mask: NewJSONMaskWithOptions(WithSuffixFields("_token")), path: "/a/access_token", expect: true},
+		{name: "should match key glob", mask: NewJSONMaskWithOptions(WithKeyGlob("user_*_id")), path: "/user_42_id", expect: true},
+		{name: "should match scoped field beneath the prefix", mask: NewJSONMaskWithOptions(WithFieldsUnder("/payments", "card")), path: "/payments[0]/card", expect: true},
+		{name: "should not match scoped field outside the prefix", mask: NewJSONMaskWithOptions(WithFieldsUnder("/payments", "card")), path: "/user/card", expect: false},
+		{name: "should match field case-insensitive", mask: NewJSONMaskWithOptions(WithFields("SSN", "/User/Name"), WithCaseInsensitive()), path: "/user/name", expect: true},
+		{name: "should match root value", mask: NewJSONMask("/"), path: "/", expect: true},
+		{name: "should match field of root array item", mask: NewJSONMask("/[0]/ssn"), path: "/[0]/ssn", expect: true},
+		{name: "should match field of root array item by legacy xpath", mask: NewJSONMask("[0]/ssn"), path: "[0]/ssn", expect: true},
+		{name: "should not match malformed xpath", mask: NewJSONMask("ssn"), path: "/a//ssn", expect: false},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			if got := tt.mask.Matches(tt.path); got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMatchesMinDepth(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithMinDepth("password", 2))
+
+	for path, want := range map[string]bool{"/password": false, "/db/password": true, "/[0]/password": true} {
+		if got := mask.Matches(path); got != want {
+			t.Errorf("Process() got = %v, want %v for %s", got, want, path)
+		}
+	}
+}
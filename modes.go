@@ -0,0 +1,20 @@
+package jsonmask
+
+// AddGlobalFieldForModes method for adding a global field which is masked only by MaskMode with one of the modes,
+// fields added by NewJSONMask or options are masked in every mode and by Mask
+func (j *JsonMask) AddGlobalFieldForModes(name string, modes ...string) {
+	for _, mode := range modes {
+		fields, ok := j.modeGlobalFields[mode]
+		if !ok {
+			fields = make(map[string]struct{})
+			j.modeGlobalFields[mode] = fields
+		}
+
+		fields[j.fold(name)] = struct{}{}
+	}
+}
+
+// MaskMode method for masking JSON fields like Mask, but also with global fields added for the mode
+func (j *JsonMask) MaskMode(mode string, value string) (string, error) {
+	return j.maskJSONString(&state{mode: mode}, value)
+}
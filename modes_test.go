@@ -0,0 +1,59 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaskMode(t *testing.T) {
+	mask := NewJSONMask("password")
+	mask.AddGlobalFieldForModes("token", "request")
+	mask.AddGlobalFieldForModes("email", "response", "request")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	value := `{"password": "pass", "token": "abc", "user": {"email": "a@b", "token": "def"}}`
+
+	tests := []struct {
+		name   string
+		mode   string
+		expect string
+	}{
+		{
+			name:   "should apply request rules",
+			mode:   "request",
+			expect: `{"password":"****","token":"***","user":{"email":"***","token":"***"}}`,
+		},
+		{
+			name:   "should apply response rules",
+			mode:   "response",
+			expect: `{"password":"****","token":"abc","user":{"email":"***","token":"def"}}`,
+		},
+		{
+			name:   "should apply only common rules for unknown mode",
+			mode:   "other",
+			expect: `{"password":"****","token":"abc","user":{"email":"a@b","token":"def"}}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := mask.MaskMode(tt.mode, value)
+			if err != nil {
+				t.Errorf("MaskMode() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("MaskMode() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+
+	got, err := mask.Mask(value)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"password":"****","token":"abc","user":{"email":"a@b","token":"def"}}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
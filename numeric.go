@@ -0,0 +1,139 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// numericString is a number parsed from a string value by WithNumericStrings
+type numericString struct {
+	value    float64
+	grouping bool
+	decimals int
+}
+
+// hasNumberFunc method for checking that a number value by the path could be masked
+func (j *JsonMask) hasNumberFunc(path string) bool {
+	int64Fn, intFn := j.integerFuncs(path)
+	return int64Fn != nil || intFn != nil || j.float64Func(path) != nil
+}
+
+// parseNumericString method for parsing the string with the configured separators ([-]1,234.56),
+// groups of the grouping separator must have three digits
+func (j *JsonMask) parseNumericString(val string) (numericString, bool) {
+	intPart, fracPart, hasFrac := strings.Cut(val, string(j.decimalSep))
+	neg := strings.HasPrefix(intPart, "-")
+	if neg {
+		intPart = intPart[1:]
+	}
+
+	groups := strings.Split(intPart, string(j.groupingSep))
+	for i, group := range groups {
+		if !isDigits(group) || len(group) > 3 && len(groups) > 1 || i > 0 && len(group) != 3 {
+			return numericString{}, false
+		}
+	}
+
+	if hasFrac && !isDigits(fracPart) {
+		return numericString{}, false
+	}
+
+	canonical := strings.Join(groups, "")
+	if hasFrac {
+		canonical += "." + fracPart
+	}
+	if neg {
+		canonical = "-" + canonical
+	}
+
+	v, err := strconv.ParseFloat(canonical, 64)
+	if err != nil {
+		return numericString{}, false
+	}
+
+	return numericString{value: v, grouping: len(groups) > 1, decimals: len(fracPart)}, true
+}
+
+// maskNumericString method for masking the numeric string by the number funcs and formatting the result back
+// with the decimal places of the original value ("1234.50" -> "1200.00")
+func (j *JsonMask) maskNumericString(st *state, k, fk, v string, num numericString, ignoreGlobal bool) (any, error) {
+	res, err := j.maskNumber(st, k, fk, num.value, "", ignoreGlobal)
+	if err != nil {
+		return nil, err
+	}
+
+	var canonical string
+	switch r := res.(type) {
+	case int:
+		canonical = strconv.Itoa(r) + zeroDecimals(num.decimals)
+	case int64:
+		canonical = strconv.FormatInt(r, 10) + zeroDecimals(num.decimals)
+	case float64:
+		if r == num.value {
+			return v, nil
+		}
+
+		if num.decimals > 0 {
+			canonical = strconv.FormatFloat(r, 'f', num.decimals, 64)
+		} else {
+			canonical = strconv.FormatFloat(r, 'f', -1, 64)
+		}
+	case json.Number: // formatted by WithFloatFormatter
+		canonical = r.String()
+	default:
+		return res, nil
+	}
+
+	return j.formatNumericString(canonical, num), nil
+}
+
+// formatNumericString method for formatting the canonical number (-1234.56) with the configured separators
+func (j *JsonMask) formatNumericString(canonical string, num numericString) string {
+	sign := ""
+	if strings.HasPrefix(canonical, "-") {
+		sign, canonical = "-", canonical[1:]
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(canonical, ".")
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, c := range intPart {
+		if num.grouping && i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteRune(j.groupingSep)
+		}
+		b.WriteRune(c)
+	}
+
+	if hasFrac {
+		b.WriteRune(j.decimalSep)
+		b.WriteString(fracPart)
+	}
+
+	return b.String()
+}
+
+// zeroDecimals returns the decimal part of n zeros (.00) for the canonical number
+func zeroDecimals(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return "." + strings.Repeat("0", n)
+}
+
+// isDigits method for checking that the string is not empty and consists of ascii digits only
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,72 @@
+package jsonmask
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestWithNumericStrings(t *testing.T) {
+	double := MaskFloat64Func(func(_ string, value float64) (float64, error) { return value * 2, nil })
+
+	tests := []struct {
+		name    string
+		value   string
+		mask    *JsonMask
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:    "should mask numeric strings with default separators",
+			mask:    NewJSONMaskWithOptions(WithFields("a", "b", "c", "d", "e"), WithNumericStrings()),
+			value:   `{"a": "1,234.56", "b": "-0.25", "c": "1.234,56", "d": "abc", "e": "12,34", "f": "1.5"}`,
+			expect:  `{"a":"2,469.12","b":"-0.50","c":"********","d":"***","e":"*****","f":"1.5"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask numeric strings with european separators",
+			mask:    NewJSONMaskWithOptions(WithFields("a", "b", "c"), WithNumericStrings(), WithNumericSeparators(',', '.')),
+			value:   `{"a": "1.234,56", "b": "500.000,5", "c": "1,234.56"}`,
+			expect:  `{"a":"2.469,12","b":"1.000.001,0","c":"********"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask numeric strings as strings without option",
+			mask:    NewJSONMaskWithOptions(WithFields("a")),
+			value:   `{"a": "1,234.56"}`,
+			expect:  `{"a":"********"}`,
+			wantErr: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskFloat64Func(double)
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestNumericStringsKeepDecimals(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithFields("balance", "limit", "fee", "count"), WithNumericStrings())
+	mask.RegisterMaskIntFunc(func(_ string, value int) (int, error) { return value / 100 * 100, nil })
+	mask.RegisterMaskFloat64Func(func(_ string, value float64) (float64, error) { return math.Round(value/100) * 100, nil })
+
+	got, err := mask.Mask(`{"balance": "1234.50", "limit": "1,949.999", "fee": "0.5", "count": "1234", "rest": "1234.50"}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"balance":"1200.00","count":"1200","fee":"0.0","limit":"1,900.000","rest":"1234.50"}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
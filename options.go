@@ -0,0 +1,597 @@
+package jsonmask
+
+import (
+	"hash"
+	"strings"
+)
+
+// Option is a func type for configuring JsonMask by NewJSONMaskWithOptions
+type Option func(*JsonMask)
+
+// NewJSONMaskWithOptions initializes a JsonMask configured by the passed options
+func NewJSONMaskWithOptions(opts ...Option) *JsonMask {
+	m := &JsonMask{
+		pathFields:       make(map[string]struct{}),
+		globalFields:     make(map[string]struct{}),
+		modeGlobalFields: make(map[string]map[string]struct{}),
+		collapseArrays:   make(map[string]struct{}),
+		shuffleArrays:    make(map[string]struct{}),
+		truncateArrays:   make(map[string]int),
+		digitArrays:      make(map[string]struct{}),
+		hashSubtrees:     make(map[string]struct{}),
+		minDepths:        make(map[string]int),
+		shallowFields:    make(map[string]struct{}),
+		markers:          make(map[string]string),
+		pathMarkers:      make(map[string]string),
+		redactedFields:   make(map[string]struct{}),
+		redactedPaths:    make(map[string]struct{}),
+		redactValues:     make(map[string]struct{}),
+		decimalSep:       '.',
+		groupingSep:      ',',
+		pathStringFuncs:  make(map[string]MaskStringFunc),
+		pathAssignFuncs:  make(map[string]MaskAssignFunc),
+		pathIntFuncs:     make(map[string]MaskIntFunc),
+		pathInt64Funcs:   make(map[string]MaskInt64Func),
+		pathFloat64Funcs: make(map[string]MaskFloat64Func),
+		pathValueFuncs:   make(map[string]MaskValueFunc),
+		objectFuncs:      make(map[string]MaskObjectFunc),
+		pathTypes:        make(map[string]string),
+
+		maskedObjectFuncs: make(map[string]MaskObjectFunc),
+		arrayFuncs:        make(map[string]MaskArrayFunc),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.foldFields()
+
+	return m
+}
+
+// WithFields adds global and xpath fields the same way as NewJSONMask does,
+// fields containing "/" are xpath fields, others are global
+func WithFields(fields ...string) Option {
+	return func(j *JsonMask) {
+		j.addFields(fields...)
+	}
+}
+
+// WithGlobalFields adds global fields, all encountered json fields with these keys will be masked
+func WithGlobalFields(fields ...string) Option {
+	return func(j *JsonMask) {
+		for _, field := range fields {
+			j.globalFields[field] = struct{}{}
+		}
+	}
+}
+
+// WithPathFields adds xpath fields (/a/b[0]/c), only fields by these paths will be masked
+func WithPathFields(paths ...string) Option {
+	return func(j *JsonMask) {
+		for _, path := range paths {
+			j.pathFields[path] = struct{}{}
+		}
+	}
+}
+
+// WithFieldsUnder masks fields with the keys only anywhere beneath the xpath prefix (/user),
+// values of the same keys outside the prefix are left as is
+func WithFieldsUnder(prefix string, fields ...string) Option {
+	return func(j *JsonMask) {
+		under := underFields{prefix: strings.TrimSuffix(prefix, pathKey), fields: make(map[string]struct{}, len(fields))}
+		if under.prefix == "" {
+			under.prefix = pathKey
+		}
+
+		for _, field := range fields {
+			under.fields[field] = struct{}{}
+		}
+
+		j.underFields = append(j.underFields, under)
+	}
+}
+
+// WithDiscriminatorRule masks target fields of objects whose discriminator key has the string value
+// ({"type":"card","number":"4111"} with "type", "card", "number"), the whole value of a target field is masked
+func WithDiscriminatorRule(discriminatorKey, discriminatorValue string, targetFields ...string) Option {
+	return func(j *JsonMask) {
+		rule := discriminatorRule{key: discriminatorKey, value: discriminatorValue, fields: make(map[string]struct{}, len(targetFields))}
+		for _, field := range targetFields {
+			rule.fields[field] = struct{}{}
+		}
+
+		j.discriminators = append(j.discriminators, rule)
+	}
+}
+
+// WithClassificationRule masks fields of objects whose classification key has one of the maskWhen string values
+// ({"classification":"secret","owner":"bob"} with "classification", []string{"secret"}), all other members
+// of the object are masked without fields. Objects with other classifications are masked as usual
+func WithClassificationRule(key string, maskWhen []string, fields ...string) Option {
+	return func(j *JsonMask) {
+		for _, value := range maskWhen {
+			rule := discriminatorRule{key: key, value: value, fields: make(map[string]struct{}, len(fields)), siblings: len(fields) == 0}
+			for _, field := range fields {
+				rule.fields[field] = struct{}{}
+			}
+
+			j.discriminators = append(j.discriminators, rule)
+		}
+	}
+}
+
+// WithCaseInsensitive matches keys of global, prefix/suffix and xpath fields ignoring case
+func WithCaseInsensitive() Option {
+	return func(j *JsonMask) {
+		j.caseInsensitive = true
+	}
+}
+
+// WithIndexAgnosticPaths matches xpath fields and funcs ignoring array indices of the traversed path,
+// so /orders/items/price matches /orders[2]/items[5]/price
+func WithIndexAgnosticPaths() Option {
+	return func(j *JsonMask) {
+		j.indexAgnostic = true
+	}
+}
+
+// WithOrderedKeys keeps the key order of the input objects and their duplicate keys ({"a":1,"a":2}) in the output,
+// each occurrence of a duplicate key is matched and masked separately by the same xpath.
+// Without the option objects are emitted with sorted keys and only the last duplicate is kept
+func WithOrderedKeys() Option {
+	return func(j *JsonMask) {
+		j.orderedKeys = true
+	}
+}
+
+// WithSortedKeyTraversal masks fields of decoded objects in sorted key order instead of the random map order,
+// so order-dependent funcs like MaskTokenize and MaskUnique assign the same values on every call
+func WithSortedKeyTraversal() Option {
+	return func(j *JsonMask) {
+		j.sortedKeys = true
+	}
+}
+
+// WithCopyUnmatchedVerbatim masks JSON by walking its raw bytes instead of decoding and encoding the whole document,
+// only masked values are rewritten and everything else (whitespace, key order, escapes, numbers) is copied byte for byte
+func WithCopyUnmatchedVerbatim() Option {
+	return func(j *JsonMask) {
+		j.verbatim = true
+	}
+}
+
+// WithDisabled initializes JsonMask with masking turned off, see SetEnabled
+func WithDisabled() Option {
+	return func(j *JsonMask) {
+		j.disabled.Store(true)
+	}
+}
+
+// WithOriginalsCapture allows MaskWithOriginals returning original values of masked fields,
+// it's meant for verifying rules in non-production environments only
+func WithOriginalsCapture() Option {
+	return func(j *JsonMask) {
+		j.captureOriginals = true
+	}
+}
+
+// WithPrefixFields masks all fields whose key starts with one of the prefixes (like global fields)
+func WithPrefixFields(prefixes ...string) Option {
+	return func(j *JsonMask) {
+		j.prefixFields = append(j.prefixFields, prefixes...)
+	}
+}
+
+// WithSuffixFields masks all fields whose key ends with one of the suffixes (like global fields)
+func WithSuffixFields(suffixes ...string) Option {
+	return func(j *JsonMask) {
+		j.suffixFields = append(j.suffixFields, suffixes...)
+	}
+}
+
+// WithKeyGlob masks all fields whose key matches one of the path.Match patterns (user_*_token) like global fields,
+// special characters of keys are escaped by backslash (price\[usd\])
+func WithKeyGlob(patterns ...string) Option {
+	return func(j *JsonMask) {
+		j.keyGlobs = append(j.keyGlobs, patterns...)
+	}
+}
+
+// WithClampNonFiniteFloats clamps NaN/Inf results of MaskFloat64Func instead of returning ErrNonFiniteFloat,
+// NaN becomes 0 and ±Inf becomes ±math.MaxFloat64
+func WithClampNonFiniteFloats() Option {
+	return func(j *JsonMask) {
+		j.clampNonFinite = true
+	}
+}
+
+// WithMaskNull replaces matched null values with the placeholder ("[REDACTED]") instead of skipping them,
+// unmatched null values are left as is
+func WithMaskNull(placeholder any) Option {
+	return func(j *JsonMask) {
+		j.maskNulls = true
+		j.nullPlaceholder = placeholder
+	}
+}
+
+// WithCollapseArrays replaces arrays by the xpath with the number of their items ("contacts":[...] -> "contacts":5)
+// instead of masking the items
+func WithCollapseArrays(paths ...string) Option {
+	return func(j *JsonMask) {
+		for _, path := range paths {
+			j.collapseArrays[path] = struct{}{}
+		}
+	}
+}
+
+// WithDigitArrayFields masks arrays of single digits by the xpath ([1,2,3,4]) as a whole number by the integer func,
+// the masked number is written back as digits. Arrays with other items are masked as usual
+func WithDigitArrayFields(paths ...string) Option {
+	return func(j *JsonMask) {
+		for _, path := range paths {
+			j.digitArrays[path] = struct{}{}
+		}
+	}
+}
+
+// WithHashSubtree replaces objects and arrays by the xpath with the hex SHA-256 of their canonical JSON
+// (sorted keys, compact), the hash of the original content is stable regardless of the key order.
+// Primitive values by the xpath are masked as usual
+func WithHashSubtree(paths ...string) Option {
+	return func(j *JsonMask) {
+		for _, path := range paths {
+			j.hashSubtrees[path] = struct{}{}
+		}
+	}
+}
+
+// WithShuffleArrays shuffles items of arrays by the xpath after masking them to break index-based joins
+// of parallel arrays, the order is changed intentionally. The permutation is derived from the seed
+// and the xpath of the array, so it's the same for every call, the last seed is used by repeated options
+func WithShuffleArrays(seed int64, paths ...string) Option {
+	return func(j *JsonMask) {
+		j.shuffleSeed = seed
+		for _, path := range paths {
+			j.shuffleArrays[path] = struct{}{}
+		}
+	}
+}
+
+// WithTruncateArrays keeps only the first max items of arrays by the xpath longer than max, the kept items are masked
+// and the string marker "...(+N more)" with the number of dropped items is appended as the last item
+// ([1,2,3,4] is [1,2,"...(+2 more)"] with max 2). Arrays with max or fewer items are kept as is,
+// a negative max is treated as 0
+func WithTruncateArrays(max int, paths ...string) Option {
+	return func(j *JsonMask) {
+		if max < 0 {
+			max = 0
+		}
+
+		for _, path := range paths {
+			j.truncateArrays[path] = max
+		}
+	}
+}
+
+// WithMaxArrayDepth makes masking fail with ErrMaxArrayDepth if arrays are nested deeper than n ([[[1]]] has depth 3),
+// objects between the arrays aren't counted. There is no limit by default
+func WithMaxArrayDepth(n int) Option {
+	return func(j *JsonMask) {
+		j.maxArrayDepth = n
+	}
+}
+
+// WithMaxValueLength caps work and output size of string masks, string values longer than n bytes
+// are truncated before masking or fail with ErrValueTooLong by the policy
+func WithMaxValueLength(n int, policy LongValuePolicy) Option {
+	return func(j *JsonMask) {
+		j.maxValueLength = n
+		j.longValuePolicy = policy
+	}
+}
+
+// WithUTF8Policy sets the behavior for invalid UTF-8 sequences of string values, they are replaced with U+FFFD
+// or fail with ErrInvalidUTF8 (UTF8Keep by default)
+func WithUTF8Policy(policy UTF8Policy) Option {
+	return func(j *JsonMask) {
+		j.utf8Policy = policy
+	}
+}
+
+// WithMaskMarkers wraps masked values of the global and xpath fields split the same way as in NewJSONMask
+// into an object documenting the mask ({"kind":"hash","masked":true,"value":"..."}), it changes the value shape
+// so it's applied only to these fields. Fields must be matched by other rules to be masked
+func WithMaskMarkers(kind string, fields ...string) Option {
+	return func(j *JsonMask) {
+		for _, field := range fields {
+			if strings.Contains(field, pathKey) {
+				j.pathMarkers[field] = kind
+			} else {
+				j.markers[field] = kind
+			}
+		}
+	}
+}
+
+// WithRedactedBlock moves masked values of the global and xpath fields split the same way as in NewJSONMask
+// out of their objects into the top-level object by key ({"_redacted":{"/user/ssn":"***"}}), it restructures
+// the document so WithCopyUnmatchedVerbatim doesn't apply. Fields must be matched by other rules to be masked,
+// array items and values of non-object documents are masked in place. Masking fails with ErrRedactedBlockKey
+// if the root object already has the key and there are values to move
+func WithRedactedBlock(key string, fields ...string) Option {
+	return func(j *JsonMask) {
+		j.redactedBlock = key
+		for _, field := range fields {
+			if strings.Contains(field, pathKey) {
+				j.redactedPaths[field] = struct{}{}
+			} else {
+				j.redactedFields[field] = struct{}{}
+			}
+		}
+	}
+}
+
+// WithKeyMaskScope limits masking keys by RegisterMaskKeyFunc to members under the xpath prefixes
+// ("/users" masks keys of "/users/..." and "/users[0]/..."), values are masked everywhere regardless of it
+func WithKeyMaskScope(prefixes ...string) Option {
+	return func(j *JsonMask) {
+		j.keyScopes = append(j.keyScopes, prefixes...)
+	}
+}
+
+// WithDigestHash sets the hash algorithm of MaskWithDigest instead of SHA-256 (sha512.New, sha1.New)
+func WithDigestHash(fn func() hash.Hash) Option {
+	return func(j *JsonMask) {
+		j.digestHash = fn
+	}
+}
+
+// WithFloatFormatter sets the text of numbers masked by MaskFloat64Func (strconv.FormatFloat(f, 'f', 2, 64)
+// for two decimals), it must return a valid JSON number. Numeric strings of WithNumericStrings are formatted
+// by it too and get the configured separators, unmasked numbers keep their representation
+func WithFloatFormatter(fn func(float64) string) Option {
+	return func(j *JsonMask) {
+		j.floatFormatter = fn
+	}
+}
+
+// WithMinDepth masks the global field only at the depth or deeper, the depth is the number of objects and arrays
+// holding the value, so top-level fields have depth 1 and "/a[0]/name" has depth 3
+func WithMinDepth(name string, depth int) Option {
+	return func(j *JsonMask) {
+		j.globalFields[name] = struct{}{}
+		j.minDepths[name] = depth
+	}
+}
+
+// WithShallowFields adds global fields masked non-recursively, an object of the field has only its direct leaf
+// values masked while nested objects and arrays are left to the others rules ({"a":"*","b":{"c":"d"}})
+func WithShallowFields(fields ...string) Option {
+	return func(j *JsonMask) {
+		for _, field := range fields {
+			j.globalFields[field] = struct{}{}
+			j.shallowFields[field] = struct{}{}
+		}
+	}
+}
+
+// WithPooledDecoding reuses maps and slices of decoded objects and arrays and traversal frames through sync.Pool
+// by Mask and MaskBytes to reduce allocations of similarly-shaped documents, MaskAny doesn't pool values it gets
+// and MaskWithOriginals doesn't pool values it returns. The pools are safe for concurrent use, pooled maps keep
+// the capacity of the largest document they held and funcs mustn't retain decoded objects or arrays after they return
+func WithPooledDecoding() Option {
+	return func(j *JsonMask) {
+		j.pooled = true
+	}
+}
+
+// WithPostValidate checks the masked output of Mask, MaskBytes and stream methods by the validator before
+// returning it (schema validation), its error is wrapped with ErrPostValidate and the output isn't returned.
+// Unchanged output is validated too, disabled masking skips it
+func WithPostValidate(validate func(masked []byte) error) Option {
+	return func(j *JsonMask) {
+		j.postValidate = validate
+	}
+}
+
+// WithOnUnknownType calls the callback with the xpath and the Go type name ("int", "time.Time") of values
+// of unexpected types passed to MaskAny instead of failing, the values are left untouched and masking continues
+func WithOnUnknownType(fn func(path string, goType string)) Option {
+	return func(j *JsonMask) {
+		j.onUnknownType = fn
+	}
+}
+
+// WithRedactValues masks all string values equal to one of the values (known secret tokens)
+// by the string func regardless of their fields
+func WithRedactValues(values ...string) Option {
+	return func(j *JsonMask) {
+		for _, val := range values {
+			j.redactValues[val] = struct{}{}
+		}
+	}
+}
+
+// WithExpectedTopLevelKeys makes masking fail with ErrUnexpectedKey if the root object has other keys than expected,
+// it guards against schema changes slipping unmasked data through
+func WithExpectedTopLevelKeys(keys ...string) Option {
+	return func(j *JsonMask) {
+		j.expectedKeys = make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			j.expectedKeys[k] = struct{}{}
+		}
+	}
+}
+
+// WithPathType sets the expected json type (TypeString, TypeNumber, ...) of the value by the xpath,
+// a value of another type is handled by the policy of WithTypeMismatchPolicy (TypeMismatchError by default)
+func WithPathType(path, jsonType string) Option {
+	return func(j *JsonMask) {
+		j.pathTypes[path] = jsonType
+	}
+}
+
+// WithTypeMismatchPolicy sets the behavior for values with unexpected json type of WithPathType
+func WithTypeMismatchPolicy(policy TypeMismatchPolicy) Option {
+	return func(j *JsonMask) {
+		j.mismatchPolicy = policy
+	}
+}
+
+// WithStrictArrayTypes makes masking fail with ErrTypeMismatch if the same key of object items of an array
+// has values of different json types ([{"v":"x"},{"v":5}]) to detect schema drift, null values are allowed
+func WithStrictArrayTypes() Option {
+	return func(j *JsonMask) {
+		j.strictArrayTypes = true
+	}
+}
+
+// WithNumericStrings masks matched strings holding numbers ("1,234.56") by the number funcs,
+// the masked number is re-emitted as a string with the same separators and decimal places
+func WithNumericStrings() Option {
+	return func(j *JsonMask) {
+		j.numericStrings = true
+	}
+}
+
+// WithNumericSeparators sets decimal and grouping separators of numeric strings masked by WithNumericStrings,
+// by default they are '.' and ',' (1,234.56), use ',' and '.' for 1.234,56
+func WithNumericSeparators(decimal, grouping rune) Option {
+	return func(j *JsonMask) {
+		j.decimalSep = decimal
+		j.groupingSep = grouping
+	}
+}
+
+// foldFields method for normalizing already configured fields, xpath get the root array form "/[0]"
+// and everything is folded when matching is case-insensitive
+func (j *JsonMask) foldFields() {
+	globalFields := make(map[string]struct{}, len(j.globalFields))
+	for field := range j.globalFields {
+		globalFields[j.fold(field)] = struct{}{}
+	}
+	j.globalFields = globalFields
+
+	minDepths := make(map[string]int, len(j.minDepths))
+	for field, depth := range j.minDepths {
+		minDepths[j.fold(field)] = depth
+	}
+	j.minDepths = minDepths
+
+	shallowFields := make(map[string]struct{}, len(j.shallowFields))
+	for field := range j.shallowFields {
+		shallowFields[j.fold(field)] = struct{}{}
+	}
+	j.shallowFields = shallowFields
+
+	pathFields := make(map[string]struct{}, len(j.pathFields))
+	for field := range j.pathFields {
+		pathFields[j.foldPath(field)] = struct{}{}
+	}
+	j.pathFields = pathFields
+
+	if j.expectedKeys != nil {
+		expectedKeys := make(map[string]struct{}, len(j.expectedKeys))
+		for k := range j.expectedKeys {
+			expectedKeys[j.fold(k)] = struct{}{}
+		}
+		j.expectedKeys = expectedKeys
+	}
+
+	pathTypes := make(map[string]string, len(j.pathTypes))
+	for path, typ := range j.pathTypes {
+		pathTypes[j.foldPath(path)] = typ
+	}
+	j.pathTypes = pathTypes
+
+	collapseArrays := make(map[string]struct{}, len(j.collapseArrays))
+	for path := range j.collapseArrays {
+		collapseArrays[j.foldPath(path)] = struct{}{}
+	}
+	j.collapseArrays = collapseArrays
+
+	markers := make(map[string]string, len(j.markers))
+	for field, kind := range j.markers {
+		markers[j.fold(field)] = kind
+	}
+	j.markers = markers
+
+	pathMarkers := make(map[string]string, len(j.pathMarkers))
+	for path, kind := range j.pathMarkers {
+		pathMarkers[j.foldPath(path)] = kind
+	}
+	j.pathMarkers = pathMarkers
+
+	for i, prefix := range j.keyScopes {
+		j.keyScopes[i] = j.foldPath(prefix)
+	}
+
+	redactedFields := make(map[string]struct{}, len(j.redactedFields))
+	for field := range j.redactedFields {
+		redactedFields[j.fold(field)] = struct{}{}
+	}
+	j.redactedFields = redactedFields
+
+	redactedPaths := make(map[string]struct{}, len(j.redactedPaths))
+	for path := range j.redactedPaths {
+		redactedPaths[j.foldPath(path)] = struct{}{}
+	}
+	j.redactedPaths = redactedPaths
+
+	hashSubtrees := make(map[string]struct{}, len(j.hashSubtrees))
+	for path := range j.hashSubtrees {
+		hashSubtrees[j.foldPath(path)] = struct{}{}
+	}
+	j.hashSubtrees = hashSubtrees
+
+	digitArrays := make(map[string]struct{}, len(j.digitArrays))
+	for path := range j.digitArrays {
+		digitArrays[j.foldPath(path)] = struct{}{}
+	}
+	j.digitArrays = digitArrays
+
+	shuffleArrays := make(map[string]struct{}, len(j.shuffleArrays))
+	for path := range j.shuffleArrays {
+		shuffleArrays[j.foldPath(path)] = struct{}{}
+	}
+	j.shuffleArrays = shuffleArrays
+
+	truncateArrays := make(map[string]int, len(j.truncateArrays))
+	for path, limit := range j.truncateArrays {
+		truncateArrays[j.foldPath(path)] = limit
+	}
+	j.truncateArrays = truncateArrays
+
+	for i := range j.prefixFields {
+		j.prefixFields[i] = j.fold(j.prefixFields[i])
+	}
+
+	for i := range j.suffixFields {
+		j.suffixFields[i] = j.fold(j.suffixFields[i])
+	}
+
+	for i := range j.keyGlobs {
+		j.keyGlobs[i] = j.fold(j.keyGlobs[i])
+	}
+
+	for i, rule := range j.discriminators {
+		fields := make(map[string]struct{}, len(rule.fields))
+		for field := range rule.fields {
+			fields[j.fold(field)] = struct{}{}
+		}
+
+		rule.key, rule.fields = j.fold(rule.key), fields
+		j.discriminators[i] = rule
+	}
+
+	for i, under := range j.underFields {
+		fields := make(map[string]struct{}, len(under.fields))
+		for field := range under.fields {
+			fields[j.fold(field)] = struct{}{}
+		}
+
+		j.underFields[i] = underFields{prefix: j.foldPath(under.prefix), fields: fields}
+	}
+}
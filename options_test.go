@@ -0,0 +1,868 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewJSONMaskWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		mask    *JsonMask
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:    "should mask fields by key suffix at any depth",
+			mask:    NewJSONMaskWithOptions(WithSuffixFields("password", "Password")),
+			value:   `{"password": "a", "oldPassword": "b", "user": {"passwordConfirm": "c", "creds": [{"newPassword": "d"}]}}`,
+			expect:  `{"oldPassword":"*","password":"*","user":{"creds":[{"newPassword":"*"}],"passwordConfirm":"c"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask fields by key prefix at any depth",
+			mask:    NewJSONMaskWithOptions(WithPrefixFields("secret_")),
+			value:   `{"secret_key": "a", "secret": "b", "nested": {"secret_token": "c", "my_secret_id": "d", "secret_obj": {"id": "e"}}}`,
+			expect:  `{"nested":{"my_secret_id":"d","secret_obj":{"id":"*"},"secret_token":"*"},"secret":"b","secret_key":"*"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should combine fields with prefix and suffix options",
+			mask:    NewJSONMaskWithOptions(WithFields("name", "/meta/id"), WithPrefixFields("x-"), WithSuffixFields("_ssn")),
+			value:   `{"name": "a", "x-token": "b", "user_ssn": "c", "meta": {"id": "d", "ssn": "e"}}`,
+			expect:  `{"meta":{"id":"*","ssn":"e"},"name":"*","user_ssn":"*","x-token":"*"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask global and xpath fields passed by separate options",
+			mask:    NewJSONMaskWithOptions(WithGlobalFields("token"), WithPathFields("/user/name")),
+			value:   `{"token": "a", "name": "b", "user": {"name": "c", "token": "d"}}`,
+			expect:  `{"name":"b","token":"*","user":{"name":"*","token":"*"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should match keys ignoring case",
+			mask:    NewJSONMaskWithOptions(WithFields("token", "/User/Name"), WithSuffixFields("password"), WithCaseInsensitive()),
+			value:   `{"TOKEN": "a", "oldPassword": "b", "user": {"NAME": "c", "Token": "d", "id": "e"}}`,
+			expect:  `{"TOKEN":"*","oldPassword":"*","user":{"NAME":"*","Token":"*","id":"e"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should match keys with case by default",
+			mask:    NewJSONMaskWithOptions(WithFields("token", "/User/Name")),
+			value:   `{"TOKEN": "a", "user": {"NAME": "c", "token": "d"}}`,
+			expect:  `{"TOKEN":"a","user":{"NAME":"c","token":"*"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask fields only under the prefix",
+			mask:    NewJSONMaskWithOptions(WithFieldsUnder("/user", "ssn", "dob")),
+			value:   `{"ssn": "a", "user": {"ssn": "b", "name": "c", "address": {"dob": "d"}, "kids": [{"ssn": "e"}]}, "userX": {"ssn": "f"}, "admin": {"dob": "g"}}`,
+			expect:  `{"admin":{"dob":"g"},"ssn":"a","user":{"address":{"dob":"*"},"kids":[{"ssn":"*"}],"name":"c","ssn":"*"},"userX":{"ssn":"f"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask fields under array prefix and ignoring case",
+			mask:    NewJSONMaskWithOptions(WithFieldsUnder("/Users/", "SSN"), WithCaseInsensitive()),
+			value:   `{"users": [{"ssn": "a", "name": "b"}, {"Ssn": "c"}], "ssn": "d"}`,
+			expect:  `{"ssn":"d","users":[{"name":"b","ssn":"*"},{"Ssn":"*"}]}`,
+			wantErr: false,
+		},
+		{
+			name:    "should match xpath fields ignoring indices of nested arrays",
+			mask:    NewJSONMaskWithOptions(WithPathFields("/orders/items/price", "/grid/cell"), WithIndexAgnosticPaths()),
+			value:   `{"orders": [{"items": [{"price": "a", "name": "b"}]}, {"items": [{"price": "c"}, {"price": "d"}]}], "grid": [[{"cell": "e"}], [{"cell": "f"}]], "price": "g"}`,
+			expect:  `{"grid":[[{"cell":"*"}],[{"cell":"*"}]],"orders":[{"items":[{"name":"b","price":"*"}]},{"items":[{"price":"*"},{"price":"*"}]}],"price":"g"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should replace matched null values with placeholder",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn", "/user/dob"), WithMaskNull("[REDACTED]")),
+			value:   `{"ssn": null, "dob": null, "user": {"dob": null, "ssn": "a", "list": [{"ssn": null}]}}`,
+			expect:  `{"dob":null,"ssn":"[REDACTED]","user":{"dob":"[REDACTED]","list":[{"ssn":"[REDACTED]"}],"ssn":"*"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should skip null values by default",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn")),
+			value:   `{"ssn": null, "user": {"ssn": null}}`,
+			expect:  `{"ssn": null, "user": {"ssn": null}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should collapse arrays to the number of items",
+			mask:    NewJSONMaskWithOptions(WithFields("name"), WithCollapseArrays("/user/contacts", "/emails", "/list/ids")),
+			value:   `{"emails": [], "user": {"name": "a", "contacts": [{"name": "b"}, "c", 1]}, "list": [{"ids": [1, 2]}], "other": [1, 2]}`,
+			expect:  `{"emails":0,"list":[{"ids":[1,2]}],"other":[1,2],"user":{"contacts":3,"name":"*"}}`,
+			wantErr: false,
+		},
+		{
+			name:    "should collapse arrays ignoring indices and case",
+			mask:    NewJSONMaskWithOptions(WithCollapseArrays("/List/IDs"), WithIndexAgnosticPaths(), WithCaseInsensitive()),
+			value:   `{"list": [{"ids": [1, 2]}, {"ids": []}]}`,
+			expect:  `{"list":[{"ids":2},{"ids":0}]}`,
+			wantErr: false,
+		},
+		{
+			name:    "should redact secret values in unlisted fields",
+			mask:    NewJSONMaskWithOptions(WithFields("token"), WithRedactValues("s3cr3t", "hunter2")),
+			value:   `{"token": "a", "note": "s3cr3t", "list": ["hunter2", "s3cr3t!", {"debug": "hunter2"}], "s3cr3t": "b"}`,
+			expect:  `{"list":["*******","s3cr3t!",{"debug":"*******"}],"note":"******","s3cr3t":"b","token":"*"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask fields by key glob at any depth",
+			mask:    NewJSONMaskWithOptions(WithKeyGlob("user_*_token", "pin?", `price\[usd\]`, `a\*b`)),
+			value:   `{"user_api_token": "a", "user_token": "b", "nested": {"user_x_token": "c", "pin1": "d", "pin12": "e"}, "price[usd]": "f", "priceu": "g", "a*b": "h", "axb": "i"}`,
+			expect:  `{"a*b":"*","axb":"i","nested":{"pin1":"*","pin12":"e","user_x_token":"*"},"price[usd]":"*","priceu":"g","user_api_token":"*","user_token":"b"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should match xpath fields with indices by default",
+			mask:    NewJSONMaskWithOptions(WithPathFields("/orders/items/price")),
+			value:   `{"orders": [{"items": [{"price": "a"}]}]}`,
+			expect:  `{"orders": [{"items": [{"price": "a"}]}]}`,
+			wantErr: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithClampNonFiniteFloats(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		fn      MaskFloat64Func
+		expect  string
+		wantErr error
+	}{
+		{
+			name:    "should return error with path for NaN result",
+			mask:    NewJSONMask("ratio"),
+			fn:      testMaskRandomFloat64(math.NaN()),
+			wantErr: ErrNonFiniteFloat,
+		},
+		{
+			name:   "should clamp NaN result to zero",
+			mask:   NewJSONMaskWithOptions(WithFields("ratio"), WithClampNonFiniteFloats()),
+			fn:     testMaskRandomFloat64(math.NaN()),
+			expect: `{"meta":{"ratio":0},"ratio":0}`,
+		},
+		{
+			name:   "should clamp Inf result to max float",
+			mask:   NewJSONMaskWithOptions(WithFields("ratio"), WithClampNonFiniteFloats()),
+			fn:     testMaskRandomFloat64(math.Inf(-1)),
+			expect: `{"meta":{"ratio":-1.7976931348623157e+308},"ratio":-1.7976931348623157e+308}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskFloat64Func(tt.fn)
+
+			got, err := tt.mask.Mask(`{"ratio": 0.5, "meta": {"ratio": 1.5}}`)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithExpectedTopLevelKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		mask    *JsonMask
+		expect  string
+		wantErr error
+	}{
+		{
+			name:   "should mask document with expected keys",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithExpectedTopLevelKeys("id", "ssn", "meta")),
+			value:  `{"id": 1, "ssn": "a", "meta": {"extra": "b"}}`,
+			expect: `{"id":1,"meta":{"extra":"b"},"ssn":"*"}`,
+		},
+		{
+			name:    "should return error on unexpected keys",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithExpectedTopLevelKeys("id", "ssn")),
+			value:   `{"id": 1, "ssn": "a", "email": "b", "phone": "c"}`,
+			wantErr: ErrUnexpectedKey,
+		},
+		{
+			name:    "should return error on unexpected keys with ordered keys",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithExpectedTopLevelKeys("id"), WithOrderedKeys()),
+			value:   `{"id": 1, "ssn": "a"}`,
+			wantErr: ErrUnexpectedKey,
+		},
+		{
+			name:    "should return error on unexpected keys with verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithExpectedTopLevelKeys("id"), WithCopyUnmatchedVerbatim()),
+			value:   `{"id": {"ssn": "a"}, "ssn": "a"}`,
+			wantErr: ErrUnexpectedKey,
+		},
+		{
+			name:   "should match expected keys ignoring case",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithExpectedTopLevelKeys("ID", "SSN"), WithCaseInsensitive()),
+			value:  `{"id": 1, "Ssn": "a"}`,
+			expect: `{"Ssn":"*","id":1}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithDisabled(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithFields("name"), WithDisabled())
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	value := `{"name": "bob", "list": [{"name": "alice"}]}`
+
+	got, err := mask.Mask(value)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if got != value {
+		t.Errorf("Process() got = %v, want %v", got, value)
+	}
+
+	if _, err := mask.Mask(`{"name":`); err == nil {
+		t.Errorf("Process() error = nil, want error on invalid json")
+	}
+
+	anyValue := map[string]any{"name": "bob"}
+	if res, err := mask.MaskAny(anyValue); err != nil || res.(map[string]any)["name"] != "bob" {
+		t.Errorf("MaskAny() got = %v, error = %v, want unchanged", res, err)
+	}
+
+	mask.SetEnabled(true)
+
+	got, err = mask.Mask(value)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if expect := `{"list":[{"name":"*****"}],"name":"***"}`; got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+
+	mask.SetEnabled(false)
+
+	if got, _ := mask.Mask(value); got != value {
+		t.Errorf("Process() got = %v, want %v", got, value)
+	}
+}
+
+func TestWithShuffleArrays(t *testing.T) {
+	const value = `{"ids":[1,2,3,4,5,6,7,8,9,10],"names":["a","b","c","d","e","f","g","h","i","j"],"ssn":["1","2"]}`
+
+	mask := func(seed int64, opts ...Option) map[string][]any {
+		m := NewJSONMaskWithOptions(append(opts, WithFields("ssn"), WithShuffleArrays(seed, "/ids", "/names"))...)
+		m.RegisterMaskStringFunc(MaskFilledString("*"))
+
+		got, err := m.Mask(value)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		var res map[string][]any
+		if err := json.Unmarshal([]byte(got), &res); err != nil {
+			t.Fatalf("json unmarshal: %v", err)
+		}
+
+		return res
+	}
+
+	var src map[string][]any
+	if err := json.Unmarshal([]byte(value), &src); err != nil {
+		t.Fatalf("json unmarshal: %v", err)
+	}
+
+	got := mask(1)
+	for _, key := range []string{"ids", "names"} {
+		sorted := append([]any(nil), got[key]...)
+		sort.Slice(sorted, func(a, b int) bool { return fmt.Sprint(sorted[a]) < fmt.Sprint(sorted[b]) })
+		want := append([]any(nil), src[key]...)
+		sort.Slice(want, func(a, b int) bool { return fmt.Sprint(want[a]) < fmt.Sprint(want[b]) })
+
+		if !reflect.DeepEqual(sorted, want) {
+			t.Errorf("Process() %s = %v, want the same items as %v", key, got[key], src[key])
+		}
+		if reflect.DeepEqual(got[key], src[key]) {
+			t.Errorf("Process() %s = %v, want shuffled order", key, got[key])
+		}
+	}
+
+	if reflect.DeepEqual(got["ids"], got["names"]) {
+		t.Errorf("Process() ids = %v, want permutation other than names", got["ids"])
+	}
+	if !reflect.DeepEqual(got["ssn"], []any{"*", "*"}) {
+		t.Errorf("Process() ssn = %v, want masked in place", got["ssn"])
+	}
+	if again := mask(1); !reflect.DeepEqual(again, got) {
+		t.Errorf("Process() got = %v, want the same order for the same seed %v", again, got)
+	}
+	if verbatim := mask(1, WithCopyUnmatchedVerbatim()); !reflect.DeepEqual(verbatim, got) {
+		t.Errorf("Process() got = %v, want the same order with verbatim copy %v", verbatim, got)
+	}
+	if other := mask(2); reflect.DeepEqual(other["ids"], got["ids"]) {
+		t.Errorf("Process() ids = %v, want other order for other seed", other["ids"])
+	}
+}
+
+func TestWithMaxArrayDepth(t *testing.T) {
+	deepObjects := strings.Repeat(`{"a":`, 100) + `[[{"ssn":"1"}]]` + strings.Repeat(`}`, 100)
+
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr error
+	}{
+		{
+			name:   "should mask arrays within the limit beneath deep objects",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithMaxArrayDepth(2)),
+			value:  deepObjects,
+			expect: strings.Repeat(`{"a":`, 100) + `[[{"ssn":"*"}]]` + strings.Repeat(`}`, 100),
+		},
+		{
+			name:    "should return error on arrays nested beyond the limit",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithMaxArrayDepth(2)),
+			value:   `{"a":[{"b":[[1]]}]}`,
+			wantErr: ErrMaxArrayDepth,
+		},
+		{
+			name:    "should return error on root arrays nested beyond the limit",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithMaxArrayDepth(3)),
+			value:   `[[[[]]]]`,
+			wantErr: ErrMaxArrayDepth,
+		},
+		{
+			name:    "should return error on arrays nested beyond the limit with verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithMaxArrayDepth(1), WithCopyUnmatchedVerbatim()),
+			value:   `{"a": [{"b": [1]}]}`,
+			wantErr: ErrMaxArrayDepth,
+		},
+		{
+			name:   "should count sibling arrays separately",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithMaxArrayDepth(1), WithCopyUnmatchedVerbatim()),
+			value:  `{"a": [1], "b": [2], "ssn": "1"}`,
+			expect: `{"a": [1], "b": [2], "ssn": "*"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithDiscriminatorRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask target field of object with matching discriminator",
+			mask:   NewJSONMaskWithOptions(WithDiscriminatorRule("type", "card", "number")),
+			value:  `{"payments":[{"type":"card","number":"4111"},{"type":"iban","number":"DE89"}],"number":"1"}`,
+			expect: `{"number":"1","payments":[{"number":"****","type":"card"},{"number":"DE89","type":"iban"}]}`,
+		},
+		{
+			name:   "should keep fields of object with non-matching discriminator",
+			mask:   NewJSONMaskWithOptions(WithDiscriminatorRule("type", "card", "number")),
+			value:  `{"type":"Card","number":"4111","nested":{"type":1,"number":"2"}}`,
+			expect: `{"type":"Card","number":"4111","nested":{"type":1,"number":"2"}}`,
+		},
+		{
+			name:   "should mask whole value of target field of root object",
+			mask:   NewJSONMaskWithOptions(WithDiscriminatorRule("kind", "person", "name", "ids"), WithOrderedKeys()),
+			value:  `{"name":"bob","kind":"person","ids":["12",{"a":"3"}],"age":"30"}`,
+			expect: `{"name":"***","kind":"person","ids":["**",{"a":"*"}],"age":"30"}`,
+		},
+		{
+			name:   "should match discriminator key case-insensitive",
+			mask:   NewJSONMaskWithOptions(WithDiscriminatorRule("Type", "card", "Number"), WithCaseInsensitive()),
+			value:  `{"TYPE":"card","number":"4111"}`,
+			expect: `{"TYPE":"card","number":"****"}`,
+		},
+		{
+			name:   "should mask target field with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithDiscriminatorRule("type", "card", "number"), WithCopyUnmatchedVerbatim()),
+			value:  `[{"number": "4111", "type": "card"}, {"number": "5", "type": "cash"}]`,
+			expect: `[{"number": "****", "type": "card"}, {"number": "5", "type": "cash"}]`,
+		},
+		{
+			name:   "should mask target fields of nested objects with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithDiscriminatorRule("type", "card", "number"), WithCopyUnmatchedVerbatim()),
+			value:  `{"number": "1", "o": {"number": "22", "x": {"type": "cash", "number": "3"}, "type": "card"}, "type": "cash", "type": "card"}`,
+			expect: `{"number": "*", "o": {"number": "**", "x": {"type": "cash", "number": "3"}, "type": "card"}, "type": "cash", "type": "card"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithDiscriminatorRuleDeepVerbatim(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithDiscriminatorRule("type", "card", "number"), WithCopyUnmatchedVerbatim())
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	depth := 4000
+	value := strings.Repeat(`{"type":"card","number":"12","o":`, depth) + `null` + strings.Repeat(`}`, depth)
+	expect := strings.Repeat(`{"type":"card","number":"**","o":`, depth) + `null` + strings.Repeat(`}`, depth)
+
+	start := time.Now()
+	got, err := mask.Mask(value)
+	if err != nil {
+		t.Fatalf("Process() error = %v, wantErr %v", err, false)
+	}
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+	// a walk decoding the subtree of every object takes seconds on this depth
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Process() took %v", elapsed)
+	}
+}
+
+func TestWithClassificationRule(t *testing.T) {
+	rule := WithClassificationRule("classification", []string{"secret", "confidential"})
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should keep siblings of public object",
+			mask:   NewJSONMaskWithOptions(rule),
+			value:  `{"classification":"public","owner":"bob","note":"hi"}`,
+			expect: `{"classification":"public","owner":"bob","note":"hi"}`,
+		},
+		{
+			name:   "should mask siblings of secret object",
+			mask:   NewJSONMaskWithOptions(rule),
+			value:  `{"classification":"secret","owner":"bob","note":{"text":"hi"}}`,
+			expect: `{"classification":"secret","note":{"text":"**"},"owner":"***"}`,
+		},
+		{
+			name:   "should mask siblings of confidential object in array",
+			mask:   NewJSONMaskWithOptions(rule),
+			value:  `{"docs":[{"classification":"confidential","owner":"bob"},{"classification":"internal","owner":"al"}]}`,
+			expect: `{"docs":[{"classification":"confidential","owner":"***"},{"classification":"internal","owner":"al"}]}`,
+		},
+		{
+			name:   "should mask only listed fields of secret object",
+			mask:   NewJSONMaskWithOptions(WithClassificationRule("classification", []string{"secret"}, "owner")),
+			value:  `{"classification":"secret","owner":"bob","note":"hi"}`,
+			expect: `{"classification":"secret","note":"hi","owner":"***"}`,
+		},
+		{
+			name:   "should mask siblings of secret object with verbatim copy",
+			mask:   NewJSONMaskWithOptions(rule, WithCopyUnmatchedVerbatim()),
+			value:  `{"owner": "bob", "classification": "secret"}`,
+			expect: `{"owner": "***", "classification": "secret"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithFloatFormatter(t *testing.T) {
+	twoDecimals := WithFloatFormatter(func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) })
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should format masked floats with two decimals",
+			mask:   NewJSONMaskWithOptions(WithFields("a", "b", "c"), twoDecimals),
+			value:  `{"a":1.5,"b":2,"c":1e-7,"d":1.50}`,
+			expect: `{"a":3.00,"b":4.00,"c":0.00,"d":1.50}`,
+		},
+		{
+			name:   "should format masked floats with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("a"), twoDecimals, WithCopyUnmatchedVerbatim()),
+			value:  `{"a": 1e21, "b": 1.0}`,
+			expect: `{"a": 2000000000000000000000.00, "b": 1.0}`,
+		},
+		{
+			name:   "should format masked numeric strings with separators",
+			mask:   NewJSONMaskWithOptions(WithFields("a"), twoDecimals, WithNumericStrings()),
+			value:  `{"a":"1,000"}`,
+			expect: `{"a":"2,000.00"}`,
+		},
+		{
+			name:   "should keep masked floats without formatter",
+			mask:   NewJSONMaskWithOptions(WithFields("a")),
+			value:  `{"a":1.25}`,
+			expect: `{"a":2.5}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskFloat64Func(func(_ string, v float64) (float64, error) { return v * 2, nil })
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithMinDepth(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should keep shallow field and mask deep one",
+			mask:   NewJSONMaskWithOptions(WithMinDepth("password", 2)),
+			value:  `{"password":"a","db":{"password":"bb","conn":{"password":"c"}}}`,
+			expect: `{"db":{"conn":{"password":"*"},"password":"**"},"password":"a"}`,
+		},
+		{
+			name:   "should count arrays in the depth",
+			mask:   NewJSONMaskWithOptions(WithMinDepth("password", 3)),
+			value:  `{"users":[{"password":"a"}],"db":{"password":"b"}}`,
+			expect: `{"db":{"password":"b"},"users":[{"password":"*"}]}`,
+		},
+		{
+			name:   "should keep shallow field with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithMinDepth("password", 2), WithCopyUnmatchedVerbatim()),
+			value:  `{"password": "a", "db": [{"password": "b"}], "x": {"password": "c"}}`,
+			expect: `{"password": "a", "db": [{"password": "*"}], "x": {"password": "*"}}`,
+		},
+		{
+			name:   "should mask shallow field matched by other rules",
+			mask:   NewJSONMaskWithOptions(WithMinDepth("password", 2), WithPrefixFields("pass"), WithKeyGlob("db_*")),
+			value:  `{"password":"a","db_password":"b","db":{"password":"cc"}}`,
+			expect: `{"db":{"password":"**"},"db_password":"*","password":"*"}`,
+		},
+		{
+			name:   "should mask shallow field matched by suffix with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithMinDepth("password", 2), WithSuffixFields("word"), WithCopyUnmatchedVerbatim()),
+			value:  `{"password": "a", "name": "b"}`,
+			expect: `{"password": "*", "name": "b"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithPostValidate(t *testing.T) {
+	// validator of the schema with the numeric "age" field
+	validate := func(masked []byte) error {
+		var v struct {
+			Age json.Number `json:"age"`
+		}
+		if err := json.Unmarshal(masked, &v); err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		fn      MaskValueFunc
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:   "should return output passing validation",
+			fn:     func(_ string, _ any) (any, error) { return 0.0, nil },
+			value:  `{"age":42,"name":"bob"}`,
+			expect: `{"age":0,"name":"bob"}`,
+		},
+		{
+			name:    "should fail when mask changes type of the field",
+			fn:      func(_ string, _ any) (any, error) { return "**", nil },
+			value:   `{"age":42,"name":"bob"}`,
+			wantErr: true,
+		},
+		{
+			name:   "should validate unchanged output",
+			fn:     func(_ string, _ any) (any, error) { return "**", nil },
+			value:  `{"name":"bob"}`,
+			expect: `{"name":"bob"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMaskWithOptions(WithGlobalFields("age"), WithPostValidate(validate))
+			mask.RegisterMaskValueFunc(tt.fn)
+
+			got, err := mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrPostValidate) {
+					t.Errorf("Process() error = %v, want %v", err, ErrPostValidate)
+				}
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithTruncateArrays(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should keep array below the limit",
+			mask:   NewJSONMaskWithOptions(WithGlobalFields("ssn"), WithTruncateArrays(3, "/users")),
+			value:  `{"users":[{"ssn":"1"},{"ssn":"2"}]}`,
+			expect: `{"users":[{"ssn":"*"},{"ssn":"*"}]}`,
+		},
+		{
+			name:   "should keep array at the limit",
+			mask:   NewJSONMaskWithOptions(WithGlobalFields("ssn"), WithTruncateArrays(2, "/users")),
+			value:  `{"users":[{"ssn":"1"},{"ssn":"2"}]}`,
+			expect: `{"users":[{"ssn":"*"},{"ssn":"*"}]}`,
+		},
+		{
+			name:   "should truncate array above the limit and append marker",
+			mask:   NewJSONMaskWithOptions(WithGlobalFields("ssn"), WithTruncateArrays(1, "/users")),
+			value:  `{"users":[{"ssn":"1"},{"ssn":"2"},{"ssn":"3"}],"ids":[1,2,3]}`,
+			expect: `{"ids":[1,2,3],"users":[{"ssn":"*"},"...(+2 more)"]}`,
+		},
+		{
+			name:   "should not mask marker of masked array field",
+			mask:   NewJSONMaskWithOptions(WithGlobalFields("tags"), WithTruncateArrays(2, "/tags")),
+			value:  `{"tags":["a","bb","ccc"]}`,
+			expect: `{"tags":["*","**","...(+1 more)"]}`,
+		},
+		{
+			name:   "should truncate root array",
+			mask:   NewJSONMaskWithOptions(WithTruncateArrays(0, "/")),
+			value:  `[1,2]`,
+			expect: `["...(+2 more)"]`,
+		},
+		{
+			name:   "should treat negative limit as zero",
+			mask:   NewJSONMaskWithOptions(WithTruncateArrays(-1, "/list")),
+			value:  `{"list":[1,2],"n":[]}`,
+			expect: `{"list":["...(+2 more)"],"n":[]}`,
+		},
+		{
+			name:   "should truncate array with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithGlobalFields("ssn"), WithTruncateArrays(1, "/users"), WithCopyUnmatchedVerbatim()),
+			value:  `{"name": "bob", "users": [{"ssn":"1"}, {"ssn":"2"}]}`,
+			expect: `{"name": "bob", "users": [{"ssn":"*"},"...(+1 more)"]}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithShallowFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask nested objects of full field",
+			mask:   NewJSONMaskWithOptions(WithGlobalFields("user")),
+			value:  `{"user":{"name":"bob","address":{"city":"NY"},"tags":["a"]}}`,
+			expect: `{"user":{"address":{"city":"**"},"name":"***","tags":["*"]}}`,
+		},
+		{
+			name:   "should mask only direct leaves of shallow field",
+			mask:   NewJSONMaskWithOptions(WithShallowFields("user")),
+			value:  `{"user":{"name":"bob","address":{"city":"NY"},"tags":["a"]}}`,
+			expect: `{"user":{"address":{"city":"NY"},"name":"***","tags":["a"]}}`,
+		},
+		{
+			name:   "should keep the others rules in nested objects of shallow field",
+			mask:   NewJSONMaskWithOptions(WithShallowFields("user"), WithGlobalFields("city")),
+			value:  `{"user":{"name":"bob","address":{"city":"NY","zip":"1"}}}`,
+			expect: `{"user":{"address":{"city":"**","zip":"1"},"name":"***"}}`,
+		},
+		{
+			name:   "should mask shallow field under full field",
+			mask:   NewJSONMaskWithOptions(WithShallowFields("user"), WithGlobalFields("data")),
+			value:  `{"data":{"user":{"name":"bob","address":{"city":"NY"}}}}`,
+			expect: `{"data":{"user":{"address":{"city":"**"},"name":"***"}}}`,
+		},
+		{
+			name:   "should mask only direct leaves of shallow field with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithShallowFields("user"), WithCopyUnmatchedVerbatim()),
+			value:  `{"user": {"name": "bob", "address": {"city": "NY"}, "tags": ["a"]}}`,
+			expect: `{"user": {"name": "***", "address": {"city": "NY"}, "tags": ["a"]}}`,
+		},
+		{
+			name:   "should mask objects of shallow array field",
+			mask:   NewJSONMaskWithOptions(WithShallowFields("users")),
+			value:  `{"users":[{"name":"bob","address":{"city":"NY"}}]}`,
+			expect: `{"users":[{"address":{"city":"NY"},"name":"***"}]}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithOnUnknownType(t *testing.T) {
+	type custom struct{ ID int }
+
+	var got []string
+	mask := NewJSONMaskWithOptions(WithGlobalFields("ssn", "id"), WithOnUnknownType(func(path, goType string) {
+		got = append(got, path+" "+goType)
+	}))
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	value := map[string]any{
+		"ssn":  "123",
+		"id":   7,
+		"list": []any{"a", custom{ID: 1}},
+	}
+
+	res, err := mask.MaskAny(value)
+	if err != nil {
+		t.Fatalf("Process() error = %v, wantErr %v", err, false)
+	}
+
+	expect := map[string]any{"ssn": "***", "id": 7, "list": []any{"a", custom{ID: 1}}}
+	if !reflect.DeepEqual(res, expect) {
+		t.Errorf("Process() got = %v, want %v", res, expect)
+	}
+
+	sort.Strings(got)
+	if want := []string{"/id int", "/list[1] jsonmask.custom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Process() got = %v, want %v", got, want)
+	}
+
+	// without the callback the unknown type fails
+	if _, err := NewJSONMask("id").MaskAny(map[string]any{"id": 7}); err == nil {
+		t.Errorf("Process() error = %v, wantErr %v", err, true)
+	}
+}
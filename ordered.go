@@ -0,0 +1,198 @@
+package jsonmask
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// orderedMember is a key-value pair of orderedObject
+type orderedMember struct {
+	key   string
+	value any
+}
+
+// orderedObject is a json object decoded by WithOrderedKeys, it keeps the order and duplicates of the keys
+type orderedObject []orderedMember
+
+// MarshalJSON method for encoding the object members in the decoded order including duplicate keys
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// maskOrderedObjectFunc method for calling MaskObjectFunc of funcs registered for the ordered object by xpath fk,
+// the object is passed as a map so that kept keys stay in place (duplicates are merged into the first one)
+// and added keys are appended in sorted order
+func (j *JsonMask) maskOrderedObjectFunc(st *state, funcs map[string]MaskObjectFunc, fk string, o orderedObject) (orderedObject, error) {
+	if _, ok := funcs[j.pathKey(rootPath(fk))]; !ok {
+		return o, nil
+	}
+
+	m := make(map[string]any, len(o))
+	for _, member := range o {
+		m[member.key] = member.value
+	}
+
+	if err := j.maskObjectFunc(st, funcs, fk, m); err != nil {
+		return nil, err
+	}
+
+	res := make(orderedObject, 0, len(m))
+	seen := make(map[string]struct{}, len(o))
+	for _, member := range o {
+		_, dup := seen[member.key]
+		seen[member.key] = struct{}{}
+
+		if val, ok := m[member.key]; ok && !dup {
+			res = append(res, orderedMember{key: member.key, value: val})
+		}
+	}
+
+	added := make([]string, 0, len(m))
+	for k := range m {
+		if _, ok := seen[k]; !ok {
+			added = append(added, k)
+		}
+	}
+
+	sort.Strings(added)
+	for _, k := range added {
+		res = append(res, orderedMember{key: k, value: m[k]})
+	}
+
+	return res, nil
+}
+
+// decodeOrdered decodes json value the same way as JsonMask.unmarshal does,
+// but objects are decoded into orderedObject
+func decodeOrdered(value []byte) (any, error) {
+	dec := newDecoder(value)
+	v, err := decodeOrderedValue(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkEOF(dec); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// newDecoder returns json decoder of the value decoding numbers into json.Number
+func newDecoder(value []byte) *json.Decoder {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+
+	return dec
+}
+
+// checkEOF checks that the decoder has no more data after the top-level value
+func checkEOF(dec *json.Decoder) error {
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid data after top-level value at offset %d", dec.InputOffset())
+	}
+
+	return nil
+}
+
+// orderedFrame is an object or array on the stack of decodeOrderedValue, key is the key of the object member
+// being decoded
+type orderedFrame struct {
+	object bool
+	o      orderedObject
+	sl     []any
+	key    string
+}
+
+// decodeOrderedValue decodes next json value from the decoder, nested objects and arrays are decoded
+// with the explicit stack so the nesting depth isn't limited by the goroutine stack
+func decodeOrderedValue(dec *json.Decoder) (any, error) {
+	var stack []orderedFrame
+	for {
+		tok, err := nextToken(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			val  any
+			done bool
+		)
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, orderedFrame{object: true, o: orderedObject{}})
+		case json.Delim('['):
+			stack = append(stack, orderedFrame{sl: []any{}})
+		case json.Delim('}'), json.Delim(']'):
+			fr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if done = true; fr.object {
+				val = fr.o
+			} else {
+				val = fr.sl
+			}
+		default:
+			val, done = tok, true
+		}
+
+		// the finished value is added to the innermost unfinished object or array
+		if done {
+			if len(stack) == 0 {
+				return val, nil
+			}
+
+			if fr := &stack[len(stack)-1]; fr.object {
+				fr.o = append(fr.o, orderedMember{key: fr.key, value: val})
+			} else {
+				fr.sl = append(fr.sl, val)
+			}
+		}
+
+		// the key of the next object member is read before its value
+		if fr := &stack[len(stack)-1]; fr.object && dec.More() {
+			key, err := nextToken(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			fr.key = key.(string)
+		}
+	}
+}
+
+// nextToken returns next json token of the value, the end of the input is unexpected inside the value
+func nextToken(dec *json.Decoder) (json.Token, error) {
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return tok, err
+}
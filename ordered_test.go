@@ -0,0 +1,71 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWithOrderedKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		mask    *JsonMask
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:    "should mask each occurrence of duplicate keys",
+			mask:    NewJSONMaskWithOptions(WithFields("a", "/user/ssn"), WithOrderedKeys()),
+			value:   `{"b": "x", "a": "1", "a": "2", "user": {"ssn": "3", "id": 4, "ssn": "5"}, "list": [{"a": "6", "a": "7"}]}`,
+			expect:  `{"b":"x","a":"*","a":"*","user":{"ssn":"*","id":4,"ssn":"*"},"list":[{"a":"*","a":"*"}]}`,
+			wantErr: false,
+		},
+		{
+			name:    "should keep key order of nested objects under global field",
+			mask:    NewJSONMaskWithOptions(WithFields("user"), WithOrderedKeys()),
+			value:   `{"z": 1, "user": {"name": "a", "tags": ["b"], "name": "c"}, "a": 2}`,
+			expect:  `{"z":1,"user":{"name":"*","tags":["*"],"name":"*"},"a":2}`,
+			wantErr: false,
+		},
+		{
+			name:    "should keep only the last duplicate key by default",
+			mask:    NewJSONMaskWithOptions(WithFields("a")),
+			value:   `{"b": "x", "a": "1", "a": "2"}`,
+			expect:  `{"a":"*","b":"x"}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask primitive root value",
+			mask:    NewJSONMaskWithOptions(WithFields("/"), WithOrderedKeys()),
+			value:   `"a"`,
+			expect:  `"*"`,
+			wantErr: false,
+		},
+		{
+			name:    "should return error on truncated json",
+			mask:    NewJSONMaskWithOptions(WithFields("a"), WithOrderedKeys()),
+			value:   `{"a": "1", "b": [`,
+			wantErr: true,
+		},
+		{
+			name:    "should return error on data after top-level value",
+			mask:    NewJSONMaskWithOptions(WithFields("a"), WithOrderedKeys()),
+			value:   `{"a": "1"} {"a": "2"}`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package jsonmask
+
+// MaskWithOriginals method for masking JSON value like Mask and returning the original values of the masked
+// leaves by xpath ("/user/emails[0]"), numbers are json.Number with the original representation. Matched null
+// fields are reported with nil value even if they aren't masked, so a present-but-null field has the xpath in the map
+// while an absent one hasn't.
+//
+// The map holds exactly the sensitive data the masking hides, it's meant for verifying rules in non-production
+// environments and must never be logged or stored. JsonMask must be created with WithOriginalsCapture,
+// ErrOriginalsDisabled is returned otherwise
+func (j *JsonMask) MaskWithOriginals(value string) (string, map[string]any, error) {
+	if !j.captureOriginals {
+		return "", nil, ErrOriginalsDisabled
+	}
+
+	st := &state{originals: make(map[string]any)}
+	res, err := j.maskJSONString(st, value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return res, st.originals, nil
+}
@@ -0,0 +1,103 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMaskWithOriginals(t *testing.T) {
+	tests := []struct {
+		name      string
+		mask      *JsonMask
+		value     string
+		expect    string
+		originals map[string]any
+	}{
+		{
+			name:      "should capture originals of nested and array fields",
+			mask:      NewJSONMaskWithOptions(WithFields("ssn", "/user/phones[1]", "/user/age"), WithOriginalsCapture()),
+			value:     `{"ssn":"123","user":{"name":"bob","age":42,"phones":["1","22"]},"list":[{"ssn":"4"}]}`,
+			expect:    `{"list":[{"ssn":"*"}],"ssn":"***","user":{"age":0,"name":"bob","phones":["1","**"]}}`,
+			originals: map[string]any{"/ssn": "123", "/list[0]/ssn": "4", "/user/phones[1]": "22", "/user/age": json.Number("42")},
+		},
+		{
+			name:      "should capture originals of verbatim masking",
+			mask:      NewJSONMaskWithOptions(WithFields("ssn"), WithCopyUnmatchedVerbatim(), WithOriginalsCapture()),
+			value:     `[{"ssn": "12"}, {"name": "bob"}]`,
+			expect:    `[{"ssn": "**"}, {"name": "bob"}]`,
+			originals: map[string]any{"/[0]/ssn": "12"},
+		},
+		{
+			name:      "should capture originals of collapsed arrays",
+			mask:      NewJSONMaskWithOptions(WithCollapseArrays("/a"), WithOriginalsCapture()),
+			value:     `{"a":[1,[2]],"b":[3]}`,
+			expect:    `{"a":2,"b":[3]}`,
+			originals: map[string]any{"/a": []any{json.Number("1"), []any{json.Number("2")}}},
+		},
+		{
+			name:      "should capture originals of collapsed arrays of verbatim masking",
+			mask:      NewJSONMaskWithOptions(WithCollapseArrays("/a"), WithCopyUnmatchedVerbatim(), WithOriginalsCapture()),
+			value:     `{"a": [1, [2]], "b": [3]}`,
+			expect:    `{"a": 2, "b": [3]}`,
+			originals: map[string]any{"/a": []any{json.Number("1"), []any{json.Number("2")}}},
+		},
+		{
+			name:      "should capture matched null field kept as is",
+			mask:      NewJSONMaskWithOptions(WithFields("ssn", "email", "/user/phone"), WithOriginalsCapture()),
+			value:     `{"ssn":null,"user":{"phone":null,"name":null},"list":[{"email":null}]}`,
+			expect:    `{"ssn":null,"user":{"phone":null,"name":null},"list":[{"email":null}]}`,
+			originals: map[string]any{"/ssn": nil, "/user/phone": nil, "/list[0]/email": nil},
+		},
+		{
+			name:      "should capture matched null field masked by placeholder",
+			mask:      NewJSONMaskWithOptions(WithFields("ssn"), WithMaskNull("[null]"), WithOriginalsCapture()),
+			value:     `{"ssn":null,"name":null}`,
+			expect:    `{"name":null,"ssn":"[null]"}`,
+			originals: map[string]any{"/ssn": nil},
+		},
+		{
+			name:      "should capture matched null field of verbatim masking",
+			mask:      NewJSONMaskWithOptions(WithFields("ssn"), WithCopyUnmatchedVerbatim(), WithOriginalsCapture()),
+			value:     `{"ssn": null, "name": null}`,
+			expect:    `{"ssn": null, "name": null}`,
+			originals: map[string]any{"/ssn": nil},
+		},
+		{
+			name:      "should capture nothing for unmatched fields",
+			mask:      NewJSONMaskWithOptions(WithFields("ssn"), WithOriginalsCapture()),
+			value:     `{"name": "bob"}`,
+			expect:    `{"name": "bob"}`,
+			originals: map[string]any{},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskIntFunc(func(_ string, _ int) (int, error) { return 0, nil })
+
+			got, originals, err := tt.mask.MaskWithOriginals(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+			if !reflect.DeepEqual(originals, tt.originals) {
+				t.Errorf("Process() originals = %v, want %v", originals, tt.originals)
+			}
+		})
+	}
+}
+
+func TestMaskWithOriginalsDisabled(t *testing.T) {
+	mask := NewJSONMask("ssn")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	if _, _, err := mask.MaskWithOriginals(`{"ssn":"123"}`); !errors.Is(err, ErrOriginalsDisabled) {
+		t.Errorf("Process() error = %v, want %v", err, ErrOriginalsDisabled)
+	}
+}
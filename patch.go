@@ -0,0 +1,83 @@
+package jsonmask
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// pointerEscaper escapes keys for JSON Pointer (RFC 6901)
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// PatchOp is a JSON Patch (RFC 6902) operation
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// MaskAsPatch method for masking JSON value and returning "replace" operations of the masked values instead of
+// the masked document, applied to the original they give the document Mask returns. Operations are sorted by
+// the JSON Pointer path, objects and arrays changed as a whole by object funcs, key masking, WithShuffleArrays
+// and WithTruncateArrays are replaced with one operation and the whole document is replaced when masked values
+// are moved into WithRedactedBlock
+func (j *JsonMask) MaskAsPatch(value string) ([]PatchOp, error) {
+	if j.disabled.Load() {
+		if _, err := j.Mask(value); err != nil {
+			return nil, err
+		}
+		return []PatchOp{}, nil
+	}
+
+	if err := j.checkUTF8([]byte(value)); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	v, err := j.unmarshal([]byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	st := &state{patch: []PatchOp{}}
+	res, err := j.maskRoot(st, v)
+	if err != nil {
+		return nil, fmt.Errorf("mask: %w", err)
+	}
+
+	if len(st.redacted) > 0 {
+		st.patch = append(st.patch, PatchOp{Op: "replace", Path: "", Value: res})
+	}
+
+	return replacedPatch(st.patch), nil
+}
+
+// replacedPatch returns operations sorted by the path without the ones beneath values replaced as a whole,
+// their paths may not exist in the replacing value
+func replacedPatch(ops []PatchOp) []PatchOp {
+	paths := make(map[string]struct{}, len(ops))
+	for _, op := range ops {
+		paths[op.Path] = struct{}{}
+	}
+
+	res := ops[:0]
+	for _, op := range ops {
+		if !isReplacedPath(paths, op.Path) {
+			res = append(res, op)
+		}
+	}
+
+	sort.Slice(res, func(a, b int) bool { return res[a].Path < res[b].Path })
+	return res
+}
+
+// isReplacedPath check JSON Pointer path on being beneath any of the paths
+func isReplacedPath(paths map[string]struct{}, path string) bool {
+	for path != "" {
+		path = path[:strings.LastIndex(path, pathKey)]
+		if _, ok := paths[path]; ok {
+			return true
+		}
+	}
+
+	return false
+}
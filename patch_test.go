@@ -0,0 +1,221 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// applyPatch applies replace operations to the decoded document
+func applyPatch(t *testing.T, doc any, ops []PatchOp) any {
+	for _, op := range ops {
+		if op.Path == "" {
+			doc = op.Value
+			continue
+		}
+
+		tokens := strings.Split(op.Path[1:], "/")
+		parent := doc
+		for i, token := range tokens {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			last := i == len(tokens)-1
+
+			switch p := parent.(type) {
+			case map[string]any:
+				if last {
+					p[token] = op.Value
+				}
+				parent = p[token]
+			case []any:
+				idx, err := strconv.Atoi(token)
+				if err != nil {
+					t.Fatalf("invalid index %q of %s", token, op.Path)
+				}
+				if last {
+					p[idx] = op.Value
+				}
+				parent = p[idx]
+			default:
+				t.Fatalf("invalid path %s", op.Path)
+			}
+		}
+	}
+
+	return doc
+}
+
+func TestMaskAsPatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect []PatchOp
+	}{
+		{
+			name:  "should return replace operations of masked fields",
+			value: `{"ssn":"123","user":{"a/b":"1","c~d":"22"},"phones":["1",{"ssn":"4"}],"id":7}`,
+			expect: []PatchOp{
+				{Op: "replace", Path: "/phones/0", Value: "*"},
+				{Op: "replace", Path: "/phones/1/ssn", Value: "*"},
+				{Op: "replace", Path: "/ssn", Value: "***"},
+				{Op: "replace", Path: "/user/a~1b", Value: "*"},
+				{Op: "replace", Path: "/user/c~0d", Value: "**"},
+			},
+		},
+		{
+			name:   "should return no operations without masked fields",
+			value:  `{"id":7}`,
+			expect: []PatchOp{},
+		},
+		{
+			name:   "should replace the whole primitive document",
+			value:  `"123"`,
+			expect: []PatchOp{{Op: "replace", Path: "", Value: "***"}},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("ssn", "phones", "/user/a/b", "/user/c~d", "/")
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := mask.MaskAsPatch(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+
+			var doc any
+			if err := json.Unmarshal([]byte(tt.value), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			masked, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want any
+			if err := json.Unmarshal([]byte(masked), &want); err != nil {
+				t.Fatal(err)
+			}
+
+			if patched := applyPatch(t, doc, got); !reflect.DeepEqual(patched, want) {
+				t.Errorf("Process() patched = %v, want %v", patched, want)
+			}
+		})
+	}
+}
+
+func TestMaskAsPatchReplaced(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   func() *JsonMask
+		value  string
+		expect []string
+	}{
+		{
+			name:   "should replace shuffled array",
+			mask:   func() *JsonMask { return NewJSONMaskWithOptions(WithFields("ssn"), WithShuffleArrays(3, "/list")) },
+			value:  `{"list":[{"ssn":"1"},{"ssn":"22"},{"ssn":"333"}],"ssn":"4"}`,
+			expect: []string{"/list", "/ssn"},
+		},
+		{
+			name:   "should replace truncated array",
+			mask:   func() *JsonMask { return NewJSONMaskWithOptions(WithFields("ssn"), WithTruncateArrays(1, "/list")) },
+			value:  `{"list":[{"ssn":"1"},{"ssn":"22"}]}`,
+			expect: []string{"/list"},
+		},
+		{
+			name: "should replace object with masked keys",
+			mask: func() *JsonMask {
+				m := NewJSONMask("ssn")
+				m.RegisterMaskKeyFunc(func(_, key string) (string, error) {
+					if key != "ssn" {
+						return "", ErrSkip
+					}
+					return "id", nil
+				})
+				return m
+			},
+			value:  `{"user":{"ssn":"1","name":"bob"},"list":["a"]}`,
+			expect: []string{"/user"},
+		},
+		{
+			name: "should replace object changed by object funcs",
+			mask: func() *JsonMask {
+				m := NewJSONMask("ssn")
+				m.RegisterObjectFunc("/user", func(object map[string]any) error {
+					object["added"] = "abc"
+					return nil
+				})
+				m.RegisterMaskedObjectFunc("/account", func(object map[string]any) error {
+					delete(object, "ssn")
+					return nil
+				})
+				return m
+			},
+			value:  `{"user":{"ssn":"1"},"account":{"ssn":"2","id":3},"ssn":"4"}`,
+			expect: []string{"/account", "/ssn", "/user"},
+		},
+		{
+			name: "should replace the whole document with redacted block",
+			mask: func() *JsonMask {
+				return NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn"))
+			},
+			value:  `{"user":{"ssn":"1"},"list":[{"ssn":"2"}]}`,
+			expect: []string{""},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			newMask := func() *JsonMask {
+				mask := tt.mask()
+				mask.RegisterMaskStringFunc(MaskFilledString("*"))
+				return mask
+			}
+
+			got, err := newMask().MaskAsPatch(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+
+			paths := make([]string, 0, len(got))
+			for _, op := range got {
+				paths = append(paths, op.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.expect) {
+				t.Errorf("Process() got = %v, want %v", paths, tt.expect)
+			}
+
+			var doc any
+			if err := json.Unmarshal([]byte(tt.value), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			masked, err := newMask().Mask(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want any
+			if err := json.Unmarshal([]byte(masked), &want); err != nil {
+				t.Fatal(err)
+			}
+
+			// the patch describes the output of Mask, so values of the patch are compared encoded
+			patched, err := json.Marshal(applyPatch(t, doc, got))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expect, _ := json.Marshal(want); string(patched) != string(expect) {
+				t.Errorf("Process() patched = %s, want %s", patched, expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package jsonmask
+
+// Paths returns xpath of every leaf value of the JSON document (/user/emails[0]) in the document order
+// without masking anything, it helps to pick fields for masking rules. The root value is "/" and
+// items of a root array are "/[0]" the same as in the masking rules
+func Paths(value string) ([]string, error) {
+	paths := []string{}
+
+	// empty prefix matches every key, so the walker passes all leaf values to the func
+	j := NewJSONMaskWithOptions(WithPrefixFields(""), WithOrderedKeys())
+	j.RegisterMaskValueFunc(func(path string, _ any) (any, error) {
+		paths = append(paths, path)
+		return nil, ErrSkip
+	})
+
+	if _, err := j.Mask(value); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,106 @@
+package jsonmask
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		expect  []string
+		wantErr bool
+	}{
+		{
+			name:    "should list leaf paths of nested objects and arrays",
+			value:   `{"name": "a", "user": {"ssn": "b", "emails": ["c", "d"], "empty": {}, "tags": []}, "list": [{"id": 1, "grid": [[true, null]]}]}`,
+			expect:  []string{"/name", "/user/ssn", "/user/emails[0]", "/user/emails[1]", "/list[0]/id", "/list[0]/grid[0][0]", "/list[0]/grid[0][1]"},
+			wantErr: false,
+		},
+		{
+			name:    "should list path of primitive root value",
+			value:   `"a"`,
+			expect:  []string{"/"},
+			wantErr: false,
+		},
+		{
+			name:    "should list paths of root array items with the root segment",
+			value:   `[1, {"id": 2, "tags": ["a"]}, [true]]`,
+			expect:  []string{"/[0]", "/[1]/id", "/[1]/tags[0]", "/[2][0]"},
+			wantErr: false,
+		},
+		{
+			name:    "should list paths of root object fields",
+			value:   `{"a": {"b": [{"c": 1}]}}`,
+			expect:  []string{"/a/b[0]/c"},
+			wantErr: false,
+		},
+		{
+			name:    "should return error on invalid json",
+			value:   `{"a":`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := Paths(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRootArrayPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask root array item by the root segment xpath",
+			mask:   NewJSONMask("/[1]/key", "/[0]"),
+			value:  `["a",{"key":"b","other":"c"}]`,
+			expect: `["*",{"key":"*","other":"c"}]`,
+		},
+		{
+			name:   "should mask root array item by the legacy xpath without the root segment",
+			mask:   NewJSONMask("[1]/key"),
+			value:  `["a",{"key":"b"}]`,
+			expect: `["a",{"key":"*"}]`,
+		},
+		{
+			name:   "should mask root array items by index agnostic xpath",
+			mask:   NewJSONMaskWithOptions(WithPathFields("/key"), WithIndexAgnosticPaths()),
+			value:  `[{"key":"a"},{"key":"b"}]`,
+			expect: `[{"key":"*"},{"key":"*"}]`,
+		},
+		{
+			name:   "should mask root array item verbatim by the root segment xpath",
+			mask:   NewJSONMaskWithOptions(WithPathFields("/[1]/key"), WithCopyUnmatchedVerbatim()),
+			value:  `[ "a", {"key": "b"} ]`,
+			expect: `[ "a", {"key": "*"} ]`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// pools of WithPooledDecoding, they are shared by all JsonMask instances and safe for concurrent use
+var (
+	mapPool    = sync.Pool{New: func() any { return make(map[string]any) }}
+	slicePool  = sync.Pool{New: func() any { return new([]any) }}
+	framePool  = sync.Pool{New: func() any { return new(frame) }}
+	valuesPool = sync.Pool{New: func() any { return new(pooledValues) }}
+)
+
+// pooledValues are maps and slices of the document decoded by unmarshalPooled, they are returned
+// to the pools by release after the masked value is marshaled
+type pooledValues struct {
+	maps   []map[string]any
+	slices []*[]any
+}
+
+// newMap method for getting a map of the pool, the map is made if pv is nil
+func (pv *pooledValues) newMap() map[string]any {
+	if pv == nil {
+		return make(map[string]any)
+	}
+
+	m := mapPool.Get().(map[string]any)
+	pv.maps = append(pv.maps, m)
+	return m
+}
+
+// newSlice method for getting an empty slice of the pool and its pool entry, the slice is made if pv is nil
+func (pv *pooledValues) newSlice() ([]any, *[]any) {
+	if pv == nil {
+		return []any{}, nil
+	}
+
+	p := slicePool.Get().(*[]any)
+	if *p == nil {
+		*p = make([]any, 0, 4)
+	}
+	pv.slices = append(pv.slices, p)
+	return (*p)[:0], p
+}
+
+// release method for returning the maps and slices to the pools, references to the document are cleared
+func (pv *pooledValues) release() {
+	if pv == nil {
+		return
+	}
+
+	for _, m := range pv.maps {
+		clear(m)
+		mapPool.Put(m)
+	}
+
+	for _, p := range pv.slices {
+		clear((*p)[:cap(*p)])
+		*p = (*p)[:0]
+		slicePool.Put(p)
+	}
+
+	clear(pv.maps)
+	clear(pv.slices)
+	pv.maps, pv.slices = pv.maps[:0], pv.slices[:0]
+	valuesPool.Put(pv)
+}
+
+// unmarshalPooled method for decoding JSON value like unmarshal, objects and arrays are decoded into maps
+// and slices of the pools and must be returned by release after the masked value is marshaled
+func (j *JsonMask) unmarshalPooled(value []byte) (any, *pooledValues, error) {
+	if j.orderedKeys || !json.Valid(value) {
+		v, err := j.unmarshal(value)
+		return v, nil, err
+	}
+
+	pv := valuesPool.Get().(*pooledValues)
+	return decodeValid(string(value), pv), pv, nil
+}
+
+// newPooledFrame method for getting a frame of the pool for nested objects and arrays, it keeps
+// the capacity of the keys of the previous use
+func (j *JsonMask) newPooledFrame() *frame {
+	if !j.pooled {
+		return new(frame)
+	}
+
+	fr := framePool.Get().(*frame)
+	fr.pooled = true
+	return fr
+}
+
+// releaseFrame returns the frame of the pool to it, references to the document are cleared
+func releaseFrame(fr *frame) {
+	if !fr.pooled {
+		return
+	}
+
+	clear(fr.keys)
+	*fr = frame{keys: fr.keys[:0]}
+	framePool.Put(fr)
+}
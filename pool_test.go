@@ -0,0 +1,152 @@
+package jsonmask
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithPooledDecoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask object decoded into pooled map",
+			value:  `{"fieldA":"valueA","metadata":{"fieldA":1.5,"fieldB":"b"},"list":[{"fieldA":"x"}]}`,
+			expect: `{"fieldA":"******","list":[{"fieldA":"*"}],"metadata":{"fieldA":1.5,"fieldB":"b"}}`,
+		},
+		{
+			name:   "should not keep keys of the previous document",
+			value:  `{"other":"valueA"}`,
+			expect: `{"other":"valueA"}`,
+		},
+		{
+			name:   "should mask array document",
+			value:  ` [{"fieldA":"ab"}]`,
+			expect: `[{"fieldA":"**"}]`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMaskWithOptions(WithFields("fieldA"), WithPooledDecoding())
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithPooledDecodingNested(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithFields("fieldA"), WithPooledDecoding())
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	tests := []struct {
+		value  string
+		expect string
+	}{
+		{
+			value:  `{"o":{},"list":[{"n":[1,2,3],"fieldA":"a"},{"m":{"x":[[]]}}]}`,
+			expect: `{"list":[{"fieldA":"*","n":[1,2,3]},{"m":{"x":[[]]}}],"o":{}}`,
+		},
+		{
+			value:  `[{"fieldA":"bb"},[],{"k":[{}]}]`,
+			expect: `[{"fieldA":"**"},[],{"k":[{}]}]`,
+		},
+		{
+			value:  `{"list":[{"fieldA":"c"}]}`,
+			expect: `{"list":[{"fieldA":"*"}]}`,
+		},
+	}
+	// the documents are masked several times to reuse pooled maps and slices of the previous ones
+	for n := 0; n < 3; n++ {
+		for i, tt := range tests {
+			got, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("#%d: Process() error = %v, wantErr %v", i, err, false)
+				continue
+			}
+			if got != tt.expect {
+				t.Errorf("#%d: Process() got = %v, want %v", i, got, tt.expect)
+			}
+		}
+	}
+}
+
+func TestDecodeValidPooled(t *testing.T) {
+	value := `{"a":[1,{"b":[]}],"c":{"d":"e"}}`
+
+	var expect any
+	if err := newDecoder([]byte(value)).Decode(&expect); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		pv := valuesPool.Get().(*pooledValues)
+		if got := decodeValid(value, pv); !reflect.DeepEqual(got, expect) {
+			t.Errorf("Process() got = %#v, want %#v", got, expect)
+		}
+		if len(pv.maps) != 3 || len(pv.slices) != 2 {
+			t.Errorf("Process() pooled %d maps and %d slices, want 3 and 2", len(pv.maps), len(pv.slices))
+		}
+		pv.release()
+	}
+}
+
+func TestWithPooledDecodingConcurrent(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithFields("fieldA"), WithPooledDecoding())
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for n := 0; n < 200; n++ {
+				value := fmt.Sprintf(`{"fieldA":"%d","n":{"fieldA":"%d","id":%d}}`, i, n, i)
+				expect := fmt.Sprintf(`{"fieldA":"*","n":{"fieldA":"%s","id":%d}}`, strings.Repeat("*", len(fmt.Sprint(n))), i)
+
+				got, err := mask.Mask(value)
+				if err != nil || got != expect {
+					t.Errorf("Process() got = %v, error = %v, want %v", got, err, expect)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// BenchmarkWithPooledDecoding/Default-16    	  186150	      7435 ns/op	    2072 B/op	      37 allocs/op
+// BenchmarkWithPooledDecoding/Pooled-16     	  173307	      6252 ns/op	    1112 B/op	      31 allocs/op
+func BenchmarkWithPooledDecoding(b *testing.B) {
+	json := `{"fieldA": "valueA", "metadata": {"fieldA": 1.234, "fieldB": "valueB", "fieldC": "valueC"}}`
+
+	for _, bench := range []struct {
+		name string
+		mask *JsonMask
+	}{
+		{name: "Default", mask: NewJSONMaskWithOptions(WithFields("fieldA"))},
+		{name: "Pooled", mask: NewJSONMaskWithOptions(WithFields("fieldA"), WithPooledDecoding())},
+	} {
+		bench.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = bench.mask.Mask(json)
+			}
+		})
+	}
+}
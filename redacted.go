@@ -0,0 +1,56 @@
+package jsonmask
+
+import "fmt"
+
+// newRedacted method for creating the block of WithRedactedBlock, nil if it isn't configured
+func (j *JsonMask) newRedacted() map[string]any {
+	if j.redactedBlock == "" {
+		return nil
+	}
+
+	return make(map[string]any)
+}
+
+// moveRedacted method for recording the masked value of the object member k by xpath fk in the block
+// of WithRedactedBlock, reports whether the member has to be removed from the object of the frame
+func (j *JsonMask) moveRedacted(st *state, fr *frame, k, fk string, res any) bool {
+	if st.redacted == nil || fr.sl != nil || fr.wrapper {
+		return false
+	}
+
+	if _, ok := j.redactedPaths[j.pathKey(fk)]; !ok {
+		if _, ok := j.redactedFields[j.fold(k)]; !ok {
+			return false
+		}
+	}
+
+	st.redacted[fk] = res
+	return true
+}
+
+// addRedacted method for adding the block of WithRedactedBlock to the masked root object, it isn't added if nothing
+// is moved. Returns ErrRedactedBlockKey if the object already has a member with the key of the block
+func (j *JsonMask) addRedacted(st *state, val any) (any, error) {
+	if len(st.redacted) == 0 {
+		return val, nil
+	}
+
+	switch v := val.(type) {
+	case map[string]any:
+		if _, ok := v[j.redactedBlock]; !ok {
+			v[j.redactedBlock] = st.redacted
+			return v, nil
+		}
+	case orderedObject:
+		for _, m := range v {
+			if m.key == j.redactedBlock {
+				return nil, fmt.Errorf("%s%s: %w", pathKey, j.redactedBlock, ErrRedactedBlockKey)
+			}
+		}
+		return append(v, orderedMember{key: j.redactedBlock, value: st.redacted}), nil
+	default:
+		return val, nil
+	}
+
+	return nil, fmt.Errorf("%s%s: %w", pathKey, j.redactedBlock, ErrRedactedBlockKey)
+}
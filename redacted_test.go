@@ -0,0 +1,95 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWithRedactedBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:   "should move masked fields into the redacted block",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn", "email"), WithRedactedBlock("_redacted", "ssn", "/user/email")),
+			value:  `{"ssn":"123","name":"bob","user":{"email":"a@b","ssn":"45"},"email":"c@d"}`,
+			expect: `{"_redacted":{"/ssn":"***","/user/email":"***","/user/ssn":"**"},"email":"***","name":"bob","user":{}}`,
+		},
+		{
+			name:   "should move masked fields of objects in arrays",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn")),
+			value:  `{"users":[{"ssn":"1","id":1},{"ssn":"22","id":2}]}`,
+			expect: `{"_redacted":{"/users[0]/ssn":"*","/users[1]/ssn":"**"},"users":[{"id":1},{"id":2}]}`,
+		},
+		{
+			name:   "should mask array items in place",
+			mask:   NewJSONMaskWithOptions(WithFields("phones"), WithRedactedBlock("_redacted", "phones")),
+			value:  `{"phones":["12","3"]}`,
+			expect: `{"phones":["**","*"]}`,
+		},
+		{
+			name:   "should keep unmatched fields untouched",
+			mask:   NewJSONMaskWithOptions(WithFields("email"), WithRedactedBlock("_redacted", "ssn")),
+			value:  `{"ssn":"123","email":"a"}`,
+			expect: `{"email":"*","ssn":"123"}`,
+		},
+		{
+			name:   "should move masked fields of ordered objects",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn"), WithOrderedKeys()),
+			value:  `{"ssn":"123","name":"bob","user":{"ssn":"45","id":1}}`,
+			expect: `{"name":"bob","user":{"id":1},"_redacted":{"/ssn":"***","/user/ssn":"**"}}`,
+		},
+		{
+			name:   "should move masked fields regardless of verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn"), WithCopyUnmatchedVerbatim()),
+			value:  `{"name": "bob", "ssn": "12"}`,
+			expect: `{"_redacted":{"/ssn":"**"},"name":"bob"}`,
+		},
+		{
+			name:   "should mask fields of array document in place",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn")),
+			value:  `[{"ssn":"12"}]`,
+			expect: `[{"ssn":"**"}]`,
+		},
+		{
+			name:   "should keep existing key of the block if nothing is moved",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn")),
+			value:  `{"_redacted":true,"name":"bob"}`,
+			expect: `{"_redacted":true,"name":"bob"}`,
+		},
+		{
+			name:    "should return error on existing key of the block",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn")),
+			value:   `{"_redacted":{"/a":"b"},"ssn":"12"}`,
+			wantErr: true,
+		},
+		{
+			name:    "should return error on existing key of the block of ordered object",
+			mask:    NewJSONMaskWithOptions(WithFields("ssn"), WithRedactedBlock("_redacted", "ssn"), WithOrderedKeys()),
+			value:   `{"ssn":"12","_redacted":null}`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && !errors.Is(err, ErrRedactedBlockKey) {
+				t.Errorf("Process() error = %v, want %v", err, ErrRedactedBlockKey)
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
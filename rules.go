@@ -0,0 +1,28 @@
+package jsonmask
+
+import gopath "path"
+
+// stringRule is MaskStringFunc of RegisterRuleStringFunc for fields matching the pattern
+type stringRule struct {
+	pattern string
+	// path is set for patterns matched against the xpath, they are matched against the key otherwise
+	path     bool
+	priority int
+	fn       MaskStringFunc
+}
+
+// stringRule method for getting the rule of the highest priority matching field k by xpath fk
+func (j *JsonMask) stringRule(k, fk string) (stringRule, bool) {
+	for _, rule := range j.stringRules {
+		name := j.fold(k)
+		if rule.path {
+			name = stripIndices(j.fold(fk))
+		}
+
+		if ok, _ := gopath.Match(rule.pattern, name); ok {
+			return rule, true
+		}
+	}
+
+	return stringRule{}, false
+}
@@ -0,0 +1,96 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRegisterRuleStringFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(m *JsonMask)
+		value    string
+		expect   string
+	}{
+		{
+			name: "should apply rule of the higher priority",
+			register: func(m *JsonMask) {
+				m.RegisterRuleStringFunc("*_token", 1, MaskFixedString("[key]"))
+				m.RegisterRuleStringFunc("/session/*", 2, MaskFixedString("[path]"))
+			},
+			value:  `{"session":{"auth_token":"a","id":"b"},"auth_token":"c"}`,
+			expect: `{"auth_token":"[key]","session":{"auth_token":"[path]","id":"[path]"}}`,
+		},
+		{
+			name: "should apply rule registered first with equal priorities",
+			register: func(m *JsonMask) {
+				m.RegisterRuleStringFunc("*_token", 1, MaskFixedString("[first]"))
+				m.RegisterRuleStringFunc("auth_*", 1, MaskFixedString("[second]"))
+			},
+			value:  `{"auth_token":"a","auth_id":"b"}`,
+			expect: `{"auth_id":"[second]","auth_token":"[first]"}`,
+		},
+		{
+			name: "should prefer positive priority rule over xpath func",
+			register: func(m *JsonMask) {
+				m.RegisterPathMaskStringFunc("/auth_token", MaskFixedString("[xpath]"))
+				m.RegisterRuleStringFunc("*_token", 1, MaskFixedString("[rule]"))
+			},
+			value:  `{"auth_token":"a"}`,
+			expect: `{"auth_token":"[rule]"}`,
+		},
+		{
+			name: "should prefer xpath func over zero priority rule",
+			register: func(m *JsonMask) {
+				m.RegisterPathMaskStringFunc("/auth_token", MaskFixedString("[xpath]"))
+				m.RegisterRuleStringFunc("*_token", 0, MaskFixedString("[rule]"))
+			},
+			value:  `{"auth_token":"a","refresh_token":"b"}`,
+			expect: `{"auth_token":"[xpath]","refresh_token":"[rule]"}`,
+		},
+		{
+			name: "should match xpath pattern regardless of array indices",
+			register: func(m *JsonMask) {
+				m.RegisterRuleStringFunc("/users/email", 0, MaskFixedString("[email]"))
+			},
+			value:  `{"users":[{"email":"a","name":"bob"}],"email":"b"}`,
+			expect: `{"email":"*","users":[{"email":"[email]","name":"bob"}]}`,
+		},
+		{
+			name: "should keep values of other types matching rule",
+			register: func(m *JsonMask) {
+				m.RegisterMaskValueFunc(func(_ string, _ any) (any, error) { return "[value]", nil })
+				m.RegisterRuleStringFunc("*_token", 0, MaskFixedString("[key]"))
+				m.RegisterRuleStringFunc("/session/*", 0, MaskFixedString("[path]"))
+			},
+			value:  `{"b_token":true,"n_token":5,"session":{"id":7,"ok":null}}`,
+			expect: `{"b_token":true,"n_token":5,"session":{"id":7,"ok":null}}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("email")
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.register(mask)
+
+			got, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterRuleStringFuncValidate(t *testing.T) {
+	mask := NewJSONMask()
+	mask.RegisterRuleStringFunc("key[", 0, MaskFilledString("*"))
+
+	if err := mask.Validate(); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("Process() error = %v, want %v", err, ErrInvalidPath)
+	}
+}
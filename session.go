@@ -0,0 +1,38 @@
+package jsonmask
+
+import "sync"
+
+// BatchSession holds Assigner of MaskAssignFunc funcs like MaskTokenize and MaskUnique across several masking calls,
+// so the same value gets the same token in every document of the batch and documents could be joined
+// on them. The session could be shared by several JsonMask, its calls are serialized
+type BatchSession struct {
+	mu     sync.Mutex
+	assign *Assigner
+}
+
+// NewBatchSession initializes an empty BatchSession
+func NewBatchSession() *BatchSession {
+	return &BatchSession{assign: NewAssigner()}
+}
+
+// MaskWithSession method for masking JSON value like Mask with tokens and surrogates of the session
+// instead of the per-call ones
+func (j *JsonMask) MaskWithSession(s *BatchSession, value string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return j.maskJSONString(s.state(), value)
+}
+
+// MaskBytesWithSession method for masking JSON value like MaskBytes with tokens and surrogates of the session
+func (j *JsonMask) MaskBytesWithSession(s *BatchSession, value []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return j.maskJSON(s.state(), value)
+}
+
+// state method for creating the per-call state sharing Assigner of the session
+func (s *BatchSession) state() *state {
+	return &state{assign: s.assign}
+}
@@ -0,0 +1,71 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaskWithSession(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     MaskAssignFunc
+		values []string
+		expect []string
+	}{
+		{
+			name:   "should keep tokens across documents",
+			fn:     MaskTokenize("USER_"),
+			values: []string{`{"user":["bob","alice"]}`, `{"user":["carol","bob"]}`, `{"user":"alice"}`},
+			expect: []string{`{"user":["USER_1","USER_2"]}`, `{"user":["USER_3","USER_1"]}`, `{"user":"USER_2"}`},
+		},
+		{
+			name:   "should keep surrogates across documents",
+			fn:     MaskUnique("ID_"),
+			values: []string{`{"user":"bob"}`, `{"user":["alice","bob"]}`},
+			expect: []string{`{"user":"ID_48181acd"}`, `{"user":["ID_522b276a","ID_48181acd"]}`},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("user")
+			mask.RegisterMaskAssignFunc(tt.fn)
+
+			session := NewBatchSession()
+			for n, value := range tt.values {
+				got, err := mask.MaskWithSession(session, value)
+				if err != nil {
+					t.Errorf("Process() error = %v, wantErr %v", err, false)
+					return
+				}
+				if got != tt.expect[n] {
+					t.Errorf("Process() got = %v, want %v", got, tt.expect[n])
+				}
+			}
+		})
+	}
+}
+
+func TestMaskWithSessionShared(t *testing.T) {
+	users := NewJSONMask("user")
+	users.RegisterMaskAssignFunc(MaskTokenize("USER_"))
+	orders := NewJSONMask("buyer")
+	orders.RegisterMaskAssignFunc(MaskTokenize("USER_"))
+
+	session := NewBatchSession()
+	if _, err := users.MaskWithSession(session, `{"user":"bob"}`); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	got, err := orders.MaskBytesWithSession(session, []byte(`{"buyer":"bob","id":1}`))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if expect := `{"buyer":"USER_1","id":1}`; string(got) != expect {
+		t.Errorf("Process() got = %s, want %v", got, expect)
+	}
+
+	// the common Mask call isn't affected by the session
+	if got, _ := users.Mask(`{"user":"alice"}`); got != `{"user":"USER_1"}` {
+		t.Errorf("Process() got = %v, want %v", got, `{"user":"USER_1"}`)
+	}
+}
@@ -0,0 +1,99 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseStrategy parses the descriptor of built-in MaskStringFunc:
+//
+//	hash             - MaskHashString
+//	fill:<char>      - MaskFilledString with the char
+//	fixed:<text>     - MaskFixedString with the text
+//	partial:<h>,<t>  - MaskPartialString keeping h first and t last characters
+func ParseStrategy(descriptor string) (MaskStringFunc, error) {
+	name, arg, hasArg := strings.Cut(descriptor, ":")
+
+	var (
+		fn  MaskStringFunc
+		err error
+	)
+	switch {
+	case name == "hash" && !hasArg:
+		fn = MaskHashString()
+	case name == "fill" && arg != "":
+		fn = MaskFilledString(arg)
+	case name == "fixed" && hasArg:
+		fn = MaskFixedString(arg)
+	case name == "partial" && hasArg:
+		fn, err = parsePartialStrategy(arg)
+	default:
+		err = errors.New("unknown descriptor")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidStrategy, descriptor, err)
+	}
+
+	return fn, nil
+}
+
+// parsePartialStrategy parses <head>,<tail> arguments of the partial strategy
+func parsePartialStrategy(arg string) (MaskStringFunc, error) {
+	headArg, tailArg, ok := strings.Cut(arg, ",")
+	if !ok {
+		return nil, errors.New("must be partial:<head>,<tail>")
+	}
+
+	head, err := strconv.Atoi(headArg)
+	if err != nil || head < 0 {
+		return nil, fmt.Errorf("invalid head %q", headArg)
+	}
+
+	tail, err := strconv.Atoi(tailArg)
+	if err != nil || tail < 0 {
+		return nil, fmt.Errorf("invalid tail %q", tailArg)
+	}
+
+	return MaskPartialString(head, tail, "*"), nil
+}
+
+// RegisterStrategy method for adding built-in MaskStringFunc described by ParseStrategy descriptor for xpath only
+func (j *JsonMask) RegisterStrategy(path, descriptor string) error {
+	fn, err := ParseStrategy(descriptor)
+	if err != nil {
+		return err
+	}
+
+	j.RegisterPathMaskStringFunc(path, fn)
+	return nil
+}
+
+// NewJSONMaskFromStrategies initializes a JsonMask masking xpath fields by descriptors of ParseStrategy
+// ({"/user/ssn": "hash", "/user/phone": "fill:*"}), errors of all malformed descriptors are joined
+func NewJSONMaskFromStrategies(strategies map[string]string, opts ...Option) (*JsonMask, error) {
+	m := NewJSONMaskWithOptions(opts...)
+
+	paths := make([]string, 0, len(strategies))
+	for path := range strategies {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		if err := m.RegisterStrategy(path, strategies[path]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
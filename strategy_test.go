@@ -0,0 +1,81 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewJSONMaskFromStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategies map[string]string
+		value      string
+		expect     string
+		wantErr    error
+	}{
+		{
+			name: "should mask fields by descriptors",
+			strategies: map[string]string{
+				"/user/ssn":   "hash",
+				"/user/phone": "fill:*",
+				"/user/name":  "fixed:[REDACTED]",
+				"/user/card":  "partial:4,2",
+				"/user/pin":   "partial:2,2",
+			},
+			value:  `{"user": {"ssn": "a", "phone": "555-12", "name": "bob", "card": "4111111111111111", "pin": "123", "id": "c"}}`,
+			expect: `{"user":{"card":"4111**********11","id":"c","name":"[REDACTED]","phone":"******","pin":"***","ssn":"86f7e437faa5a7fce15d1ddcb9eaeaea377667b8"}}`,
+		},
+		{
+			name:       "should return error for unknown descriptor",
+			strategies: map[string]string{"/user/ssn": "hash", "/user/phone": "blur"},
+			wantErr:    ErrInvalidStrategy,
+		},
+		{
+			name:       "should return error for malformed descriptors",
+			strategies: map[string]string{"/a": "hash:x", "/b": "fill:", "/c": "partial:2", "/d": "partial:a,1", "/e": "partial:1,-1"},
+			wantErr:    ErrInvalidStrategy,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask, err := NewJSONMaskFromStrategies(tt.strategies)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			got, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	mask := NewJSONMask()
+	if err := mask.RegisterStrategy("/token", "fixed:"); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if err := mask.RegisterStrategy("/ssn", "unknown"); !errors.Is(err, ErrInvalidStrategy) {
+		t.Errorf("Process() error = %v, wantErr %v", err, ErrInvalidStrategy)
+	}
+
+	got, err := mask.Mask(`{"token": "a", "ssn": "b"}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if expect := `{"ssn":"b","token":""}`; got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
@@ -0,0 +1,58 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaskStream method for masking JSON document from r to w preserving the original formatting, bytes of untouched
+// regions including indentation are copied as is and only masked values are rewritten (compact) as by
+// WithCopyUnmatchedVerbatim regardless of the option. The document is buffered, nothing is written if it can't be masked
+func (j *JsonMask) MaskStream(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	res, err := j.maskJSON(&state{verbatim: true}, data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(res); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// MaskConcatenated method for masking concatenated JSON documents without delimiters ({...}{...}[...]) from r to w,
+// documents are masked one by one and written in the same order separated by a newline, so primitive documents
+// (1 2) stay apart, the original whitespace between them is dropped. Masking stops on the first document which can't be decoded or masked, the previous ones are already written
+func (j *JsonMask) MaskConcatenated(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("document %d: json unmarshal: %w", n, err)
+		}
+
+		res, err := j.MaskBytes(raw)
+		if err != nil {
+			return fmt.Errorf("document %d: %w", n, err)
+		}
+
+		if n > 1 {
+			res = append([]byte{'\n'}, res...)
+		}
+
+		if _, err := w.Write(res); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+}
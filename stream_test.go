@@ -0,0 +1,115 @@
+package jsonmask
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMaskStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name: "should keep indentation of untouched regions",
+			value: `{
+    "name": "bob",
+    "user": {
+        "ssn":   "123-45",
+        "tags": [ 1,  2 ]
+    }
+}
+`,
+			expect: `{
+    "name": "bob",
+    "user": {
+        "ssn":   "******",
+        "tags": [ 1,  2 ]
+    }
+}
+`,
+		},
+		{
+			name:   "should copy document without masked values as is",
+			value:  "{\n\t\"name\": \"bob\"\n}",
+			expect: "{\n\t\"name\": \"bob\"\n}",
+		},
+		{
+			name:    "should not write invalid document",
+			value:   `{"ssn": "1"`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("ssn")
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			var buf bytes.Buffer
+			err := mask.MaskStream(strings.NewReader(tt.value), &buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got := buf.String(); got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskConcatenated(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:   "should mask concatenated objects and array",
+			value:  `{"ssn":"12","id":1}{"name":"bob"}[{"ssn":"3"}]`,
+			expect: "{\"id\":1,\"ssn\":\"**\"}\n{\"name\":\"bob\"}\n[{\"ssn\":\"*\"}]",
+		},
+		{
+			name:   "should replace whitespace between documents with newline",
+			value:  "{\"ssn\": \"1\"}\n  [1, 2]\t",
+			expect: "{\"ssn\":\"*\"}\n[1, 2]",
+		},
+		{
+			name:   "should separate primitive documents",
+			value:  `1 2 "a"3{}`,
+			expect: "1\n2\n\"a\"\n3\n{}",
+		},
+		{
+			name:   "should write nothing for empty stream",
+			value:  " ",
+			expect: "",
+		},
+		{
+			name:    "should stop on malformed document",
+			value:   `{"ssn":"1"} {"ssn":`,
+			expect:  `{"ssn":"*"}`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMask("ssn")
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			var buf bytes.Buffer
+			err := mask.MaskConcatenated(strings.NewReader(tt.value), &buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got := buf.String(); got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
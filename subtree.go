@@ -0,0 +1,119 @@
+package jsonmask
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// hashSubtree method for replacing the object or array by WithHashSubtree with the hash of its canonical JSON,
+// returns false for primitive values
+func (j *JsonMask) hashSubtree(st *state, val any) (any, bool, error) {
+	switch val.(type) {
+	case map[string]any, orderedObject, []any:
+	default:
+		return val, false, nil
+	}
+
+	b, err := canonicalJSON(val)
+	if err != nil {
+		return nil, true, err
+	}
+
+	hash := sha256.Sum256(b)
+	st.changed = true
+	return hex.EncodeToString(hash[:]), true, nil
+}
+
+// canonicalFrame is an object or array on the stack of canonicalJSON, keys are the sorted keys of the object
+type canonicalFrame struct {
+	object bool
+	m      map[string]any
+	keys   []string
+	sl     []any
+	i      int
+}
+
+// canonicalJSON returns JSON of the value with sorted keys of objects including ordered ones, the last member
+// of duplicate keys wins as in the plain decoding. It's the same as json.Marshal of maps but iterative to handle
+// deeply nested values
+func canonicalJSON(val any) ([]byte, error) {
+	var (
+		buf   bytes.Buffer
+		stack []canonicalFrame
+	)
+
+	for {
+		switch v := val.(type) {
+		case orderedObject:
+			m := make(map[string]any, len(v))
+			for _, member := range v {
+				m[member.key] = member.value
+			}
+			stack = append(stack, canonicalFrame{object: true, m: m, keys: sortedKeys(m)})
+			buf.WriteByte('{')
+		case map[string]any:
+			stack = append(stack, canonicalFrame{object: true, m: v, keys: sortedKeys(v)})
+			buf.WriteByte('{')
+		case []any:
+			stack = append(stack, canonicalFrame{sl: v})
+			buf.WriteByte('[')
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+
+		// the next value is the next member or item of the innermost unfinished object or array
+		for {
+			if len(stack) == 0 {
+				return buf.Bytes(), nil
+			}
+
+			fr := &stack[len(stack)-1]
+			if !fr.object && fr.i == len(fr.sl) || fr.object && fr.i == len(fr.keys) {
+				if fr.object {
+					buf.WriteByte('}')
+				} else {
+					buf.WriteByte(']')
+				}
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			if fr.i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if fr.object {
+				key, err := json.Marshal(fr.keys[fr.i])
+				if err != nil {
+					return nil, err
+				}
+				buf.Write(key)
+				buf.WriteByte(':')
+				val = fr.m[fr.keys[fr.i]]
+			} else {
+				val = fr.sl[fr.i]
+			}
+
+			fr.i++
+			break
+		}
+	}
+}
+
+// sortedKeys returns keys of the map in ascending order
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,109 @@
+package jsonmask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestWithHashSubtree(t *testing.T) {
+	hash := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should replace object with hash of canonical json",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithHashSubtree("/user")),
+			value:  `{"user":{"ssn":"1","name":{"last":"b","first":"a"}},"id":1}`,
+			expect: `{"id":1,"user":"` + hash(`{"name":{"first":"a","last":"b"},"ssn":"1"}`) + `"}`,
+		},
+		{
+			name:   "should hash ordered object regardless of key order",
+			mask:   NewJSONMaskWithOptions(WithHashSubtree("/user"), WithOrderedKeys()),
+			value:  `{"user":{"name":{"last":"b","first":"a"},"ssn":"1"},"id":1}`,
+			expect: `{"user":"` + hash(`{"name":{"first":"a","last":"b"},"ssn":"1"}`) + `","id":1}`,
+		},
+		{
+			name:   "should replace array with hash of canonical json",
+			mask:   NewJSONMaskWithOptions(WithHashSubtree("/tags")),
+			value:  `{"tags":[{"b":1,"a":2},"x"]}`,
+			expect: `{"tags":"` + hash(`[{"a":2,"b":1},"x"]`) + `"}`,
+		},
+		{
+			name:   "should hash subtree with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithHashSubtree("/user"), WithCopyUnmatchedVerbatim()),
+			value:  `{"id": 1, "user": {"b": 1, "a": [1.50]}}`,
+			expect: `{"id": 1, "user": "` + hash(`{"a":[1.50],"b":1}`) + `"}`,
+		},
+		{
+			name:   "should keep primitive value by the path",
+			mask:   NewJSONMaskWithOptions(WithHashSubtree("/user")),
+			value:  `{"user":"bob"}`,
+			expect: `{"user":"bob"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  any
+		expect string
+	}{
+		{
+			name:   "should sort keys of nested objects",
+			value:  map[string]any{"b": []any{map[string]any{"y": 1.5, "x": nil}}, "a": "<&>"},
+			expect: `{"a":"\u003c\u0026\u003e","b":[{"x":null,"y":1.5}]}`,
+		},
+		{
+			name:   "should keep the last member of duplicate ordered keys",
+			value:  orderedObject{{key: "b", value: true}, {key: "a", value: json.Number("1.0")}, {key: "b", value: "x"}},
+			expect: `{"a":1.0,"b":"x"}`,
+		},
+		{
+			name:   "should write empty and nil objects and arrays",
+			value:  []any{map[string]any(nil), []any(nil), map[string]any{}, []any{}, orderedObject{}},
+			expect: `[{},[],{},[],{}]`,
+		},
+		{
+			name:   "should marshal other values as is",
+			value:  map[string]any{"s": []string{"a"}, "n": 1},
+			expect: `{"n":1,"s":["a"]}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := canonicalJSON(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if string(got) != tt.expect {
+				t.Errorf("Process() got = %s, want %v", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MaskTemplate returns MaskObjectFunc setting the field of the object to the template with {key} placeholders
+// replaced by values of the object fields ("{first} {last}"), it's meant for RegisterMaskedObjectFunc
+// so the placeholders get already masked values. Strings are inserted as is, other values by their JSON text,
+// absent and null fields give an empty string. "{{" and "}}" are literal braces, an unclosed "{" is kept as is
+func MaskTemplate(field, template string) MaskObjectFunc {
+	return func(object map[string]any) error {
+		var b strings.Builder
+		for rest := template; rest != ""; {
+			i := strings.IndexAny(rest, "{}")
+			if i < 0 {
+				b.WriteString(rest)
+				break
+			}
+
+			b.WriteString(rest[:i])
+			rest = rest[i:]
+
+			switch {
+			case strings.HasPrefix(rest, "{{"), strings.HasPrefix(rest, "}}"):
+				b.WriteByte(rest[0])
+				rest = rest[2:]
+				continue
+			case rest[0] == '}':
+				b.WriteByte('}')
+				rest = rest[1:]
+				continue
+			}
+
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				b.WriteString(rest)
+				break
+			}
+
+			text, err := templateText(object[rest[1:end]])
+			if err != nil {
+				return err
+			}
+
+			b.WriteString(text)
+			rest = rest[end+1:]
+		}
+
+		object[field] = b.String()
+		return nil
+	}
+}
+
+// templateText returns text of the object field value inserted into MaskTemplate
+func templateText(val any) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
@@ -0,0 +1,85 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaskTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		object   map[string]any
+		expect   string
+	}{
+		{name: "should replace placeholders with field values", template: "{first} {last}", object: map[string]any{"first": "a", "last": "b"}, expect: "a b"},
+		{name: "should insert other values by json text", template: "{age}/{admin}/{tags}", object: map[string]any{"age": 42.0, "admin": true, "tags": []any{"x"}}, expect: `42/true/["x"]`},
+		{name: "should insert empty string for absent and null fields", template: "[{first}|{last}]", object: map[string]any{"last": nil}, expect: "[|]"},
+		{name: "should keep literal and unclosed braces", template: "{{first}} } {first", object: map[string]any{"first": "a"}, expect: "{first} } {first"},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			if err := MaskTemplate("display", tt.template)(tt.object); err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got := tt.object["display"]; got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterMaskedObjectFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		path   string
+		value  string
+		expect string
+	}{
+		{
+			name:   "should build display name from masked parts",
+			mask:   NewJSONMask("first", "last"),
+			path:   "/users[0]",
+			value:  `{"users":[{"first":"John","last":"Smith","display":"John Smith"}]}`,
+			expect: `{"users":[{"display":"**** *****","first":"****","last":"*****"}]}`,
+		},
+		{
+			name:   "should build display name of ordered root object",
+			mask:   NewJSONMaskWithOptions(WithFields("last"), WithOrderedKeys()),
+			path:   "/",
+			value:  `{"last":"Smith","first":"John"}`,
+			expect: `{"last":"*****","first":"John","display":"John *****"}`,
+		},
+		{
+			name:   "should build display name of nested ordered object",
+			mask:   NewJSONMaskWithOptions(WithFields("last"), WithOrderedKeys()),
+			path:   "/user",
+			value:  `{"user":{"display":"","last":"Smith","first":"John"},"id":1}`,
+			expect: `{"user":{"display":"John *****","last":"*****","first":"John"},"id":1}`,
+		},
+		{
+			name:   "should build display name with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("last"), WithCopyUnmatchedVerbatim()),
+			path:   "/user",
+			value:  `{"id": 1, "user": {"first": "John", "last": "Smith"}}`,
+			expect: `{"id": 1, "user": {"display":"John *****","first":"John","last":"*****"}}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskedObjectFunc(tt.path, MaskTemplate("display", "{first} {last}"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
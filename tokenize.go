@@ -0,0 +1,108 @@
+package jsonmask
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+)
+
+// uniqueHashLen is the number of hex digits of the value hash used by MaskUnique surrogates
+const uniqueHashLen = 8
+
+// Assigner holds tokens of MaskTokenize and surrogates of MaskUnique assigned to values seen before,
+// JsonMask passes a new one to MaskAssignFunc on every masking call and BatchSession keeps one across calls
+type Assigner struct {
+	// tokens are values assigned by Token by prefix and original value
+	tokens map[string]map[string]string
+	// surrogates are values assigned by Unique by prefix and original value, assigned holds all of them
+	surrogates map[string]map[string]string
+	assigned   map[string]struct{}
+}
+
+// NewAssigner initializes an empty Assigner, it isn't safe for concurrent use
+func NewAssigner() *Assigner {
+	return &Assigner{
+		tokens:     make(map[string]map[string]string),
+		surrogates: make(map[string]map[string]string),
+		assigned:   make(map[string]struct{}),
+	}
+}
+
+// MaskTokenize replaces values with prefix+N tokens (USER_1, USER_2) assigned in first-seen order,
+// identical values get the same token. Tokens start over on every Mask call (BatchSession keeps them across calls)
+func MaskTokenize(prefix string) MaskAssignFunc {
+	return func(a *Assigner, _, val string) (string, error) {
+		return a.Token(prefix, val), nil
+	}
+}
+
+// Token method for getting token of the value by prefix, a new one is assigned for the value seen first
+func (a *Assigner) Token(prefix, val string) string {
+	tokens, ok := a.tokens[prefix]
+	if !ok {
+		tokens = make(map[string]string)
+		a.tokens[prefix] = tokens
+	}
+
+	token, ok := tokens[val]
+	if !ok {
+		token = prefix + strconv.Itoa(len(tokens)+1)
+		tokens[val] = token
+	}
+
+	return token
+}
+
+// MaskUnique replaces values with prefix+hash surrogates (ID_2c26b46b) which are unique iff the originals are unique,
+// a surrogate colliding with the one of another value gets a numeric suffix (ID_2c26b46b-2).
+// Surrogates start over on every Mask call (BatchSession keeps them across calls)
+func MaskUnique(prefix string) MaskAssignFunc {
+	return func(a *Assigner, _, val string) (string, error) {
+		return a.Unique(prefix, val), nil
+	}
+}
+
+// Unique method for getting surrogate of the value by prefix, a new one is assigned for the value seen first
+func (a *Assigner) Unique(prefix, val string) string {
+	surrogates, ok := a.surrogates[prefix]
+	if !ok {
+		surrogates = make(map[string]string)
+		a.surrogates[prefix] = surrogates
+	}
+
+	if surrogate, ok := surrogates[val]; ok {
+		return surrogate
+	}
+
+	hash := sha1.Sum([]byte(val))
+	base := prefix + hex.EncodeToString(hash[:])[:uniqueHashLen]
+
+	surrogate := base
+	for i := 2; ; i++ {
+		if _, ok := a.assigned[surrogate]; !ok {
+			break
+		}
+		surrogate = base + "-" + strconv.Itoa(i)
+	}
+
+	surrogates[val] = surrogate
+	a.assigned[surrogate] = struct{}{}
+
+	return surrogate
+}
+
+// assigner method for getting Assigner of the masking call, it's created on the first use
+func (st *state) assigner() *Assigner {
+	if st.assign == nil {
+		st.assign = NewAssigner()
+	}
+
+	return st.assign
+}
+
+// bind method for getting MaskStringFunc calling fn with Assigner of the masking call
+func (st *state) bind(fn MaskAssignFunc) MaskStringFunc {
+	return func(path, val string) (string, error) {
+		return fn(st.assigner(), path, val)
+	}
+}
@@ -0,0 +1,145 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaskTokenize(t *testing.T) {
+	mask := NewJSONMask("user")
+	mask.RegisterMaskAssignFunc(MaskTokenize("USER_"))
+
+	tests := []struct {
+		name   string
+		value  string
+		expect string
+	}{
+		{
+			name:   "should assign tokens in first-seen order",
+			value:  `{"user": ["bob", "alice", "bob", "carol", "alice"]}`,
+			expect: `{"user":["USER_1","USER_2","USER_1","USER_3","USER_2"]}`,
+		},
+		{
+			name:   "should start over on every call",
+			value:  `{"user": ["carol", "dave", "carol"]}`,
+			expect: `{"user":["USER_1","USER_2","USER_1"]}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v", err)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskTokenizePrefixes(t *testing.T) {
+	mask := NewJSONMask()
+	mask.RegisterPathMaskAssignFunc("/user", MaskTokenize("USER_"))
+	mask.RegisterPathMaskAssignFunc("/org", MaskTokenize("ORG_"))
+
+	got, err := mask.Mask(`{"user": "acme", "org": "acme"}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"org":"ORG_1","user":"USER_1"}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+
+	// direct calls share tokens of the passed Assigner
+	a := NewAssigner()
+	for _, tt := range []struct{ value, expect string }{{"acme", "USER_1"}, {"inc", "USER_2"}, {"acme", "USER_1"}} {
+		if got, err := MaskTokenize("USER_")(a, "/user", tt.value); err != nil || got != tt.expect {
+			t.Errorf("MaskTokenize() got = %v, %v, want %v", got, err, tt.expect)
+		}
+	}
+}
+
+func TestRegisterMaskAssignFunc(t *testing.T) {
+	mask := NewJSONMask("user", "/name")
+	mask.RegisterPathMaskStringFunc("/name", MaskFilledString("*"))
+	mask.RegisterMaskAssignFunc(func(a *Assigner, path, val string) (string, error) {
+		if val == "" {
+			return "", ErrSkip
+		}
+		return MaskTokenize("USER_")(a, path, val)
+	})
+
+	got, err := mask.Mask(`{"user": ["bob", "", "bob"], "name": "bob"}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"name":"***","user":["USER_1","","USER_1"]}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
+
+func TestMaskUnique(t *testing.T) {
+	mask := NewJSONMask("id")
+	mask.RegisterMaskAssignFunc(MaskUnique("ID_"))
+
+	got, err := mask.MaskAny(map[string]any{"id": []any{"a", "b", "a", "c", "b"}})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	ids := got.(map[string]any)["id"].([]any)
+	if ids[0] != ids[2] || ids[1] != ids[4] {
+		t.Errorf("Process() got = %v, want equal surrogates for equal values", ids)
+	}
+	if ids[0] == ids[1] || ids[0] == ids[3] || ids[1] == ids[3] {
+		t.Errorf("Process() got = %v, want unique surrogates for unique values", ids)
+	}
+	if ids[0] != "ID_86f7e437" {
+		t.Errorf("Process() got = %v, want %v", ids[0], "ID_86f7e437")
+	}
+
+	if got, err := MaskUnique("ID_")(NewAssigner(), "/id", "a"); err != nil || got != "ID_86f7e437" {
+		t.Errorf("MaskUnique() got = %v, %v, want %v", got, err, "ID_86f7e437")
+	}
+}
+
+func TestMaskUniqueCollision(t *testing.T) {
+	// surrogate of "a" is taken by another value, e.g. on a hash collision
+	a := NewAssigner()
+	a.surrogates["ID_"] = map[string]string{"z": "ID_86f7e437"}
+	a.assigned["ID_86f7e437"] = struct{}{}
+
+	if got := a.Unique("ID_", "a"); got != "ID_86f7e437-2" {
+		t.Errorf("Process() got = %v, want %v", got, "ID_86f7e437-2")
+	}
+	if got := a.Unique("ID_", "a"); got != "ID_86f7e437-2" {
+		t.Errorf("Process() got = %v, want the same surrogate for the same value", got)
+	}
+	if got := a.Unique("ID_", "z"); got != "ID_86f7e437" {
+		t.Errorf("Process() got = %v, want %v", got, "ID_86f7e437")
+	}
+}
+
+func TestMaskTokenizeSortedKeyTraversal(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithGlobalFields("owner", "author", "viewer", "editor"), WithSortedKeyTraversal())
+	mask.RegisterMaskAssignFunc(MaskTokenize("USER_"))
+
+	value := `{"viewer":"carol","owner":"alice","meta":{"editor":"dave","author":"bob"},"author":"erin"}`
+	expect := `{"author":"USER_1","meta":{"author":"USER_2","editor":"USER_3"},"owner":"USER_4","viewer":"USER_5"}`
+
+	for i := 0; i < 20; i++ {
+		got, err := mask.Mask(value)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if got != expect {
+			t.Fatalf("Process() got = %v, want %v", got, expect)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// TypeMismatchPolicy is a behavior when a value by the xpath of WithPathType has another json type
+type TypeMismatchPolicy int
+
+const (
+	// TypeMismatchError makes masking fail with ErrTypeMismatch
+	TypeMismatchError TypeMismatchPolicy = iota
+	// TypeMismatchSkip leaves the value unchanged without masking
+	TypeMismatchSkip
+	// TypeMismatchCoerce converts the value to the expected type before masking ("30" -> 30),
+	// values that can't be converted fail with ErrTypeMismatch
+	TypeMismatchCoerce
+)
+
+// list of json types of WithPathType
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+	TypeObject  = "object"
+	TypeArray   = "array"
+)
+
+// checkPathType method for checking the value by WithPathType, returns the value to mask
+// and whether masking must be skipped by the mismatch policy
+func (j *JsonMask) checkPathType(st *state, fk string, val any) (any, bool, error) {
+	want, ok := j.pathTypes[j.pathKey(fk)]
+	if !ok {
+		return val, false, nil
+	}
+
+	got := jsonTypeOf(val)
+	if got == want {
+		return val, false, nil
+	}
+
+	switch j.mismatchPolicy {
+	case TypeMismatchSkip:
+		return val, true, nil
+	case TypeMismatchCoerce:
+		if res, ok := coerceType(val, want); ok {
+			st.changed = true
+			return res, false, nil
+		}
+	}
+
+	return nil, false, fmt.Errorf("%s: %w: want %s, got %s", rootPath(fk), ErrTypeMismatch, want, got)
+}
+
+// checkArrayTypes method for checking that fields of object items of the array by xpath pk have the same json type
+// by WithStrictArrayTypes, nulls are allowed for any type. Returns ErrTypeMismatch for the first drifted field
+func (j *JsonMask) checkArrayTypes(pk string, sl []any) error {
+	if !j.strictArrayTypes {
+		return nil
+	}
+
+	types := make(map[string]string)
+	for i, item := range sl {
+		var members []orderedMember
+		switch v := item.(type) {
+		case map[string]any:
+			for k, val := range v {
+				members = append(members, orderedMember{key: k, value: val})
+			}
+			sort.Slice(members, func(a, b int) bool { return members[a].key < members[b].key })
+		case orderedObject:
+			members = v
+		}
+
+		for _, m := range members {
+			if err := checkFieldType(pk, i, types, m.key, jsonTypeOf(m.value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkFieldType checks json type got of the field k of the item i of the array by xpath pk
+// with types of the same field of the previous items
+func checkFieldType(pk string, i int, types map[string]string, k, got string) error {
+	if got == TypeNull {
+		return nil
+	}
+
+	want, ok := types[k]
+	if !ok {
+		types[k] = got
+		return nil
+	}
+
+	if got != want {
+		return fmt.Errorf("%s[%d]%s%s: %w: want %s, got %s", pk, i, pathKey, k, ErrTypeMismatch, want, got)
+	}
+
+	return nil
+}
+
+// jsonTypeOf returns json type of the decoded value
+func jsonTypeOf(val any) string {
+	switch val.(type) {
+	case string:
+		return TypeString
+	case float64, json.Number:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	case nil:
+		return TypeNull
+	case map[string]any, orderedObject:
+		return TypeObject
+	case []any:
+		return TypeArray
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
+
+// addFieldTypes adds json types of fields of the decoded object to types if it's set
+func addFieldTypes(types map[string]string, val any) {
+	if types == nil {
+		return
+	}
+
+	switch v := val.(type) {
+	case map[string]any:
+		for k, m := range v {
+			types[k] = jsonTypeOf(m)
+		}
+	case orderedObject:
+		for _, m := range v {
+			types[m.key] = jsonTypeOf(m.value)
+		}
+	}
+}
+
+// rawTypeOf returns json type of the raw value by its first byte
+func rawTypeOf(c byte) string {
+	switch c {
+	case '"':
+		return TypeString
+	case 't', 'f':
+		return TypeBoolean
+	case 'n':
+		return TypeNull
+	case '{':
+		return TypeObject
+	case '[':
+		return TypeArray
+	default:
+		return TypeNumber
+	}
+}
+
+// coerceType converts primitive value to the json type, only strings, numbers and booleans are converted
+func coerceType(val any, typ string) (any, bool) {
+	switch typ {
+	case TypeString:
+		switch v := val.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case json.Number:
+			return v.String(), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	case TypeNumber:
+		switch v := val.(type) {
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, true
+			}
+		case bool:
+			if v {
+				return float64(1), true
+			}
+			return float64(0), true
+		}
+	case TypeBoolean:
+		if v, ok := val.(string); ok {
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,177 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithPathType(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		mask    *JsonMask
+		expect  string
+		wantErr error
+	}{
+		{
+			name:   "should mask values of expected types",
+			mask:   NewJSONMaskWithOptions(WithFields("/user/age", "/user/name"), WithPathType("/user/age", TypeNumber), WithPathType("/user", TypeObject)),
+			value:  `{"user": {"age": 30, "name": "bob"}}`,
+			expect: `{"user":{"age":1,"name":"***"}}`,
+		},
+		{
+			name:    "should return error on type mismatch by default",
+			mask:    NewJSONMaskWithOptions(WithFields("/user/age"), WithPathType("/user/age", TypeNumber)),
+			value:   `{"user": {"age": "30"}}`,
+			wantErr: ErrTypeMismatch,
+		},
+		{
+			name:    "should return error on type mismatch of object",
+			mask:    NewJSONMaskWithOptions(WithPathType("/user", TypeObject), WithTypeMismatchPolicy(TypeMismatchError)),
+			value:   `{"user": [1]}`,
+			wantErr: ErrTypeMismatch,
+		},
+		{
+			name:   "should skip values on type mismatch",
+			mask:   NewJSONMaskWithOptions(WithFields("age", "name"), WithPathType("/user/age", TypeNumber), WithTypeMismatchPolicy(TypeMismatchSkip)),
+			value:  `{"user": {"age": "30", "name": "bob"}, "age": "40"}`,
+			expect: `{"age":"**","user":{"age":"30","name":"***"}}`,
+		},
+		{
+			name:   "should coerce values on type mismatch",
+			mask:   NewJSONMaskWithOptions(WithFields("/user/age"), WithPathType("/user/age", TypeNumber), WithPathType("/user/id", TypeString), WithPathType("/user/admin", TypeBoolean), WithTypeMismatchPolicy(TypeMismatchCoerce)),
+			value:  `{"user": {"age": "30", "id": 12345678901234567890, "admin": "true"}}`,
+			expect: `{"user":{"admin":true,"age":1,"id":"12345678901234567890"}}`,
+		},
+		{
+			name:    "should return error on coerce failure",
+			mask:    NewJSONMaskWithOptions(WithFields("/user/age"), WithPathType("/user/age", TypeNumber), WithTypeMismatchPolicy(TypeMismatchCoerce)),
+			value:   `{"user": {"age": "thirty"}}`,
+			wantErr: ErrTypeMismatch,
+		},
+		{
+			name:   "should coerce values with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithPathType("/user/age", TypeNumber), WithTypeMismatchPolicy(TypeMismatchCoerce), WithCopyUnmatchedVerbatim()),
+			value:  `{"user": {"age": "30", "name": "bob"}}`,
+			expect: `{"user": {"age": 30, "name": "bob"}}`,
+		},
+		{
+			name:    "should return error on type mismatch of array with verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithPathType("/user", TypeString), WithCopyUnmatchedVerbatim()),
+			value:   `{"user": ["bob"]}`,
+			wantErr: ErrTypeMismatch,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskIntFunc(testMaskRandomInt(1))
+
+			got, err := tt.mask.Mask(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithStrictArrayTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    *JsonMask
+		value   string
+		expect  string
+		wantErr string
+	}{
+		{
+			name:   "should route mixed types of the same key to their funcs",
+			mask:   NewJSONMask("v"),
+			value:  `[{"v":"x"},{"v":5},{"v":1.5},{"v":true}]`,
+			expect: `[{"v":"*"},{"v":1},{"v":2.5},{"v":true}]`,
+		},
+		{
+			name:    "should return error on mixed types with strict flag",
+			mask:    NewJSONMaskWithOptions(WithFields("v"), WithStrictArrayTypes()),
+			value:   `{"list":[{"v":"x"},{"v":5}]}`,
+			wantErr: "/list[1]/v: type mismatch: want string, got number",
+		},
+		{
+			name:    "should return error on mixed types of root array with strict flag",
+			mask:    NewJSONMaskWithOptions(WithFields("v"), WithStrictArrayTypes(), WithOrderedKeys()),
+			value:   `[{"v":1},{"a":1},{"v":{}}]`,
+			wantErr: "/[2]/v: type mismatch: want number, got object",
+		},
+		{
+			name:    "should return error on mixed types with strict flag and verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithFields("v"), WithStrictArrayTypes(), WithCopyUnmatchedVerbatim()),
+			value:   `{"list": [{"v": "x"}, {"v": [1]}]}`,
+			wantErr: "/list[1]/v: type mismatch: want string, got array",
+		},
+		{
+			name:    "should return error on mixed types of decoded items with strict flag and verbatim copy",
+			mask:    NewJSONMaskWithOptions(WithFields("v"), WithStrictArrayTypes(), WithCopyUnmatchedVerbatim(), WithPathType("/list[1]", TypeObject)),
+			value:   `{"list": [{"v": "x"}, {"v": 1}]}`,
+			wantErr: "/list[1]/v: type mismatch: want string, got number",
+		},
+		{
+			name:   "should allow nulls and consistent types with strict flag and verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("v"), WithStrictArrayTypes(), WithCopyUnmatchedVerbatim()),
+			value:  `[{"v": "x", "v": null}, {"w": 1, "v": "yz"}, [{"w": "a"}], {"w": 2}]`,
+			expect: `[{"v": "*", "v": null}, {"w": 1, "v": "**"}, [{"w": "a"}], {"w": 2}]`,
+		},
+		{
+			name:   "should allow nulls and consistent types with strict flag",
+			mask:   NewJSONMaskWithOptions(WithFields("v"), WithStrictArrayTypes()),
+			value:  `[{"v":"x"},{"v":null},{"w":1},{"v":"yz"},"a",[1]]`,
+			expect: `[{"v":"*"},{"v":null},{"w":1},{"v":"**"},"a",[1]]`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskIntFunc(testMaskRandomInt(1))
+			tt.mask.RegisterMaskFloat64Func(func(_ string, v float64) (float64, error) { return v + 1, nil })
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != (tt.wantErr != "") || err != nil && !strings.HasSuffix(err.Error(), tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && !errors.Is(err, ErrTypeMismatch) {
+				t.Errorf("Process() error = %v, want %v", err, ErrTypeMismatch)
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithStrictArrayTypesDeepVerbatim(t *testing.T) {
+	mask := NewJSONMaskWithOptions(WithFields("v"), WithStrictArrayTypes(), WithCopyUnmatchedVerbatim())
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	depth := 4000
+	value := strings.Repeat(`[{"v":"ab"},`, depth) + `null` + strings.Repeat(`]`, depth)
+	expect := strings.Repeat(`[{"v":"**"},`, depth) + `null` + strings.Repeat(`]`, depth)
+
+	start := time.Now()
+	got, err := mask.Mask(value)
+	if err != nil {
+		t.Fatalf("Process() error = %v, wantErr %v", err, false)
+	}
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+	// a walk decoding the subtree of every array takes seconds on this depth
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Process() took %v", elapsed)
+	}
+}
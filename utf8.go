@@ -0,0 +1,92 @@
+package jsonmask
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// UTF8Policy is a behavior for invalid UTF-8 sequences and lone surrogate escapes ("\ud800") in string values
+type UTF8Policy int
+
+const (
+	// UTF8Keep doesn't check values, the JSON decoder replaces invalid sequences of the input with U+FFFD,
+	// but strings of MaskAny values are passed to the mask funcs as is
+	UTF8Keep UTF8Policy = iota
+	// UTF8Replace replaces invalid sequences with U+FFFD before masking, so length-based masks count runes right
+	UTF8Replace
+	// UTF8Error makes masking fail with ErrInvalidUTF8 if the input or a masked string value has invalid sequences
+	UTF8Error
+)
+
+// checkUTF8 method for checking raw JSON input by UTF8Error policy
+func (j *JsonMask) checkUTF8(value []byte) error {
+	if j.utf8Policy != UTF8Error {
+		return nil
+	}
+
+	if !utf8.Valid(value) {
+		return fmt.Errorf("%w: invalid byte sequence", ErrInvalidUTF8)
+	}
+
+	// backslashes are valid only inside strings, so escapes are found without tracking strings
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 >= len(value) {
+			continue
+		}
+
+		i++
+		if value[i] != 'u' || i+4 >= len(value) {
+			continue
+		}
+
+		r := escapedRune(value[i+1 : i+5])
+		switch {
+		case utf16.IsSurrogate(r) && r < 0xdc00: // high surrogate must be followed by low one
+			if i+10 < len(value) && value[i+5] == '\\' && value[i+6] == 'u' {
+				if low := escapedRune(value[i+7 : i+11]); low >= 0xdc00 && low <= 0xdfff {
+					i += 10
+					continue
+				}
+			}
+			return fmt.Errorf("%w: lone surrogate at offset %d", ErrInvalidUTF8, i-1)
+		case utf16.IsSurrogate(r):
+			return fmt.Errorf("%w: lone surrogate at offset %d", ErrInvalidUTF8, i-1)
+		}
+
+		i += 4
+	}
+
+	return nil
+}
+
+// escapedRune returns rune of 4 hex digits of \u escape, -1 for malformed digits
+func escapedRune(hex []byte) rune {
+	r, err := strconv.ParseUint(string(hex), 16, 16)
+	if err != nil {
+		return -1
+	}
+
+	return rune(r)
+}
+
+// validUTF8 method for applying UTF8Policy to the string value by xpath fk before masking
+func (j *JsonMask) validUTF8(fk, v string) (string, error) {
+	if j.utf8Policy == UTF8Keep || utf8.ValidString(v) {
+		return v, nil
+	}
+
+	if j.utf8Policy == UTF8Error {
+		return "", fmt.Errorf("%s: %w: invalid byte sequence", rootPath(fk), ErrInvalidUTF8)
+	}
+
+	var b []byte
+	for i := 0; i < len(v); {
+		r, size := utf8.DecodeRuneInString(v[i:])
+		b = utf8.AppendRune(b, r) // invalid bytes are decoded as U+FFFD one by one like the JSON decoder does
+		i += size
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,71 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWithUTF8Policy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  UTF8Policy
+		value   string
+		expect  string
+		wantErr error
+	}{
+		{name: "should mask decoded lone surrogate by default", policy: UTF8Keep, value: `{"a":"\ud800x"}`, expect: `{"a":"**"}`},
+		{name: "should mask valid surrogate pair", policy: UTF8Error, value: `{"a":"😀","b":"\\ud800"}`, expect: `{"a":"*","b":"\\ud800"}`},
+		{name: "should return error on lone high surrogate", policy: UTF8Error, value: `{"a":"\ud800x"}`, wantErr: ErrInvalidUTF8},
+		{name: "should return error on lone low surrogate", policy: UTF8Error, value: `{"b":"ok","a":"\ude00"}`, wantErr: ErrInvalidUTF8},
+		{name: "should return error on reversed surrogate pair", policy: UTF8Error, value: `{"a":"\ude00\ud83d"}`, wantErr: ErrInvalidUTF8},
+		{name: "should return error on invalid byte", policy: UTF8Error, value: "{\"a\":\"\xffx\"}", wantErr: ErrInvalidUTF8},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			mask := NewJSONMaskWithOptions(WithFields("a"), WithUTF8Policy(tt.policy))
+			mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := mask.Mask(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWithUTF8PolicyMaskAny(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  UTF8Policy
+		expect  string
+		wantErr error
+	}{
+		{name: "should pass invalid bytes as is by default", policy: UTF8Keep, expect: "a\xff\xfeb"},
+		{name: "should replace invalid bytes before masking", policy: UTF8Replace, expect: "a��b"},
+		{name: "should return error on invalid bytes", policy: UTF8Error, wantErr: ErrInvalidUTF8},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			var got string
+			mask := NewJSONMaskWithOptions(WithFields("a"), WithUTF8Policy(tt.policy))
+			mask.RegisterMaskStringFunc(func(_, val string) (string, error) {
+				got = val
+				return val, nil
+			})
+
+			_, err := mask.MaskAny(map[string]any{"a": "a\xff\xfeb"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,604 @@
+package jsonmask
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// verbatim walks raw bytes of a valid JSON document and rewrites only masked values,
+// everything else including whitespace, key order and number representation is copied as is
+type verbatim struct {
+	j    *JsonMask
+	st   *state
+	data []byte
+	pos  int
+	// targets are keys masked by WithDiscriminatorRule and WithClassificationRule of objects by their offsets
+	targets map[int]map[string]struct{}
+	// copied is the offset of data up to which it's already copied to out
+	copied int
+	out    bytes.Buffer
+	// stack holds objects and arrays on the current path, the walk is iterative to handle deeply nested documents
+	stack []vframe
+}
+
+// vframe is an object or array on the stack of the verbatim walk
+type vframe struct {
+	object bool
+	// k is the field of the array, pk is the xpath of the object or array
+	k, pk        string
+	ignoreGlobal bool
+	// shallow makes nested objects and arrays of the object keep ignoreGlobal as by WithShallowFields
+	shallow bool
+	depth   int
+	// keys are collected for the root object only, targets are keys masked by discriminator rules
+	keys    []string
+	targets map[string]struct{}
+	// names are masked keys of the object collected for RegisterMaskKeyFunc
+	names keyNames
+	// types are json types of fields of the object added for the array item, for an array they are json types
+	// of fields of its object items by WithStrictArrayTypes and item holds the ones of the current item
+	types, item map[string]string
+	// i is the index of the next array item
+	i int
+}
+
+// maskVerbatim method for masking JSON value by WithCopyUnmatchedVerbatim
+func (j *JsonMask) maskVerbatim(st *state, value []byte) ([]byte, error) {
+	if !json.Valid(value) {
+		_, err := j.unmarshal(value)
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	w := &verbatim{j: j, st: st, data: value}
+	if len(j.discriminators) > 0 {
+		w.scanTargets()
+	}
+
+	if err := w.root(); err != nil {
+		return nil, fmt.Errorf("mask: %w", err)
+	}
+
+	if !st.changed {
+		return value, nil
+	}
+
+	w.out.Write(value[w.copied:])
+	return w.out.Bytes(), nil
+}
+
+// root method for walking the root value the same way as maskRoot does
+func (w *verbatim) root() error {
+	w.skipSpace()
+	switch w.data[w.pos] {
+	case '{':
+		if w.hasObjectFunc(pathKey) {
+			return w.decoded(func(val any) (any, error) { return w.j.maskRoot(w.st, val) })
+		}
+		w.pushObject("", true, false, nil)
+	case '[':
+		if w.isDecodedArray(pathKey) {
+			return w.decoded(func(val any) (any, error) { return w.j.maskRoot(w.st, val) })
+		}
+		if err := w.pushArray("", pathKey, true); err != nil {
+			return err
+		}
+	default:
+		return w.leaf("", pathKey, true)
+	}
+
+	return w.walk()
+}
+
+// walk method for walking members and items of objects and arrays on the stack until it's empty
+func (w *verbatim) walk() error {
+	for len(w.stack) > 0 {
+		var err error
+		if fr := &w.stack[len(w.stack)-1]; fr.object {
+			err = w.nextMember(fr)
+		} else {
+			err = w.nextItem(fr)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// value method for walking value of field k by xpath fk the same way as maskValue does, objects and arrays
+// are pushed to the stack. Json types of fields of the object value are added to types if it's set
+func (w *verbatim) value(k, fk string, ignoreGlobal bool, types map[string]string) error {
+	w.skipSpace()
+	switch w.data[w.pos] {
+	case '{':
+		if w.isDecoded(fk) {
+			return w.decoded(func(val any) (any, error) {
+				addFieldTypes(types, val)
+				return w.j.maskValue(w.st, k, fk, val, ignoreGlobal)
+			})
+		}
+		ignoreGlobalVal := !(!ignoreGlobal || w.j.isGlobalField(w.st, k, fk))
+		w.pushObject(fk, ignoreGlobalVal, w.j.isShallow(k, ignoreGlobal, ignoreGlobalVal), types)
+		return nil
+	case '[':
+		if w.isDecodedArray(fk) {
+			return w.decoded(func(val any) (any, error) { return w.j.maskValue(w.st, k, fk, val, ignoreGlobal) })
+		}
+		if _, ok := w.j.collapseArrays[w.j.pathKey(fk)]; ok {
+			start := w.pos
+			n := w.skipArray()
+			if err := w.collapsedOriginal(fk, w.data[start:w.pos]); err != nil {
+				return err
+			}
+
+			w.replace(start, []byte(fmt.Sprint(n)))
+			w.st.changed = true
+			return nil
+		}
+		return w.pushArray(k, fk, ignoreGlobal)
+	default:
+		return w.leaf(k, fk, ignoreGlobal)
+	}
+}
+
+// collapsedOriginal method for capturing the array by xpath fk collapsed by WithCollapseArrays for
+// MaskWithOriginals, the array is decoded only if originals are captured
+func (w *verbatim) collapsedOriginal(fk string, raw []byte) error {
+	if w.st.originals == nil {
+		return nil
+	}
+
+	val, err := w.j.unmarshal(raw)
+	if err != nil {
+		return err
+	}
+
+	w.st.original(fk, val)
+	return nil
+}
+
+// isDecoded method for checking that the object by xpath fk must be decoded for masking,
+// it has MaskObjectFunc, json type of WithPathType or it's hashed by WithHashSubtree
+func (w *verbatim) isDecoded(fk string) bool {
+	_, hasType := w.j.pathTypes[w.j.pathKey(fk)]
+	_, hashed := w.j.hashSubtrees[w.j.pathKey(fk)]
+
+	return w.hasObjectFunc(fk) || hasType || hashed
+}
+
+// hasObjectFunc method for checking that the object by xpath fk has MaskObjectFunc called before
+// or after masking its fields
+func (w *verbatim) hasObjectFunc(fk string) bool {
+	_, before := w.j.objectFuncs[w.j.pathKey(fk)]
+	_, after := w.j.maskedObjectFuncs[w.j.pathKey(fk)]
+
+	return before || after
+}
+
+// isDecodedArray method for checking that the array by xpath fk must be decoded for masking,
+// it has json type of WithPathType, it's shuffled by WithShuffleArrays, it's a digit array of WithDigitArrayFields
+// it's hashed by WithHashSubtree, it's truncated by WithTruncateArrays or it has MaskArrayFunc
+func (w *verbatim) isDecodedArray(fk string) bool {
+	_, hasType := w.j.pathTypes[w.j.pathKey(fk)]
+	_, shuffled := w.j.shuffleArrays[w.j.pathKey(fk)]
+	_, digits := w.j.digitArrays[w.j.pathKey(fk)]
+	_, hashed := w.j.hashSubtrees[w.j.pathKey(fk)]
+	_, truncated := w.j.truncateArrays[w.j.pathKey(fk)]
+	_, hasFunc := w.j.arrayFuncs[w.j.pathKey(fk)]
+
+	return hasType || shuffled || digits || hashed || truncated || hasFunc
+}
+
+// pushObject method for pushing the object by xpath pk to the stack, ignoreGlobal and shallow are as in vframe
+func (w *verbatim) pushObject(pk string, ignoreGlobal, shallow bool, types map[string]string) {
+	w.stack = append(w.stack, vframe{
+		object:       true,
+		pk:           pk,
+		ignoreGlobal: ignoreGlobal,
+		shallow:      shallow,
+		depth:        w.st.depth,
+		targets:      w.targets[w.pos],
+		types:        types,
+	})
+	w.pos++ // {
+}
+
+// nextMember method for walking the next member of the object of the frame fr, the frame is popped
+// at the end of the object. The root object has empty pk
+func (w *verbatim) nextMember(fr *vframe) error {
+	w.skipSpace()
+	switch w.data[w.pos] {
+	case '}':
+		w.pos++
+		w.st.depth = fr.depth
+		w.stack = w.stack[:len(w.stack)-1]
+		if fr.pk == "" { // root object
+			return w.j.checkTopLevelKeys(fr.keys)
+		}
+		return nil
+	case ',':
+		w.pos++
+		w.skipSpace()
+	}
+
+	start := w.pos
+	w.skipString()
+
+	var key string
+	if err := json.Unmarshal(w.data[start:w.pos], &key); err != nil {
+		return err
+	}
+
+	if fr.pk == "" {
+		fr.keys = append(fr.keys, key)
+	}
+
+	fk := fr.pk + pathKey + key
+	name, err := w.j.maskKey(w.st, key, fk)
+	if err != nil {
+		return err
+	}
+
+	if w.j.maskKeyFunc != nil {
+		if fr.names == nil {
+			fr.names = make(keyNames)
+		}
+
+		if err := fr.names.add(fr.pk, name, name != key); err != nil {
+			return err
+		}
+	}
+
+	if name != key {
+		b, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		w.replace(start, b)
+	}
+
+	w.skipSpace()
+	w.pos++ // :
+	w.skipSpace()
+
+	if fr.types != nil {
+		fr.types[key] = rawTypeOf(w.data[w.pos])
+	}
+
+	ignoreGlobal := fr.ignoreGlobal || (fr.shallow && (w.data[w.pos] == '{' || w.data[w.pos] == '['))
+	if _, ok := fr.targets[w.j.fold(key)]; ok {
+		ignoreGlobal = false
+	}
+
+	// fr is invalid after value as it could push to the stack
+	w.st.depth = fr.depth + 1
+	return w.value(key, fk, ignoreGlobal, nil)
+}
+
+// scanTargets method for collecting targets of all objects of the document in one pass before walking it,
+// a target member could precede the discriminator key, so targets of an object are known only after its end
+func (w *verbatim) scanTargets() {
+	type scanFrame struct {
+		start   int
+		key     string
+		members map[string]any
+	}
+
+	var (
+		doc   = string(w.data)
+		stack []scanFrame
+	)
+	for w.pos = 0; ; {
+		w.skipSpace()
+		if w.pos == len(w.data) {
+			w.pos = 0
+			return
+		}
+
+		switch w.data[w.pos] {
+		case '{', '[':
+			if len(stack) > 0 && stack[len(stack)-1].members != nil {
+				fr := &stack[len(stack)-1]
+				fr.members[fr.key] = nil
+			}
+
+			fr := scanFrame{start: w.pos}
+			if w.data[w.pos] == '{' {
+				fr.members = make(map[string]any)
+			}
+			stack = append(stack, fr)
+			w.pos++
+		case '}', ']':
+			fr := stack[len(stack)-1]
+			if targets := w.j.objectTargets(fr.members); len(targets) > 0 {
+				if w.targets == nil {
+					w.targets = make(map[int]map[string]struct{})
+				}
+				w.targets[fr.start] = targets
+			}
+			stack = stack[:len(stack)-1]
+			w.pos++
+		case ',':
+			w.pos++
+		case '"':
+			var s string
+			s, w.pos = decodeString(doc, w.pos)
+			if len(stack) == 0 || stack[len(stack)-1].members == nil {
+				continue
+			}
+
+			fr := &stack[len(stack)-1]
+			if w.skipSpace(); w.data[w.pos] == ':' {
+				fr.key = s
+				w.pos++
+				continue
+			}
+			fr.members[fr.key] = s
+		default:
+			w.skipPrimitive()
+			if len(stack) > 0 && stack[len(stack)-1].members != nil {
+				fr := &stack[len(stack)-1]
+				fr.members[fr.key] = nil
+			}
+		}
+	}
+}
+
+// pushArray method for pushing the array of field k by xpath pk to the stack
+func (w *verbatim) pushArray(k, pk string, ignoreGlobal bool) error {
+	if err := w.j.enterArray(w.st, pk); err != nil {
+		return err
+	}
+
+	fr := vframe{k: k, pk: pk, ignoreGlobal: ignoreGlobal, depth: w.st.depth}
+	if w.j.strictArrayTypes {
+		fr.types = make(map[string]string)
+	}
+
+	w.stack = append(w.stack, fr)
+	w.pos++ // [
+	return nil
+}
+
+// nextItem method for walking the next item of the array of the frame fr, the frame is popped at the end
+// of the array. Json types of fields of object items are checked after walking them by WithStrictArrayTypes
+func (w *verbatim) nextItem(fr *vframe) error {
+	if fr.item != nil {
+		if err := checkItemTypes(fr.pk, fr.i-1, fr.types, fr.item); err != nil {
+			return err
+		}
+		fr.item = nil
+	}
+
+	w.skipSpace()
+	switch w.data[w.pos] {
+	case ']':
+		w.pos++
+		w.st.arrays--
+		w.st.depth = fr.depth
+		w.stack = w.stack[:len(w.stack)-1]
+		return nil
+	case ',':
+		w.pos++
+		w.skipSpace()
+	}
+
+	if fr.types != nil && w.data[w.pos] == '{' {
+		fr.item = make(map[string]string)
+	}
+
+	// fr is invalid after value as it could push to the stack
+	fk := fmt.Sprintf("%s[%d]", fr.pk, fr.i)
+	fr.i++
+	w.st.depth = fr.depth + 1
+	return w.value(fr.k, fk, fr.ignoreGlobal, fr.item)
+}
+
+// checkItemTypes checks json types of fields of the object item i of the array by xpath pk in order of their keys
+// as checkArrayTypes does
+func checkItemTypes(pk string, i int, types, item map[string]string) error {
+	keys := make([]string, 0, len(item))
+	for k := range item {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := checkFieldType(pk, i, types, k, item[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// leaf method for masking primitive value, the raw bytes are rewritten only if the value is masked
+func (w *verbatim) leaf(k, fk string, ignoreGlobal bool) error {
+	start := w.pos
+	w.skipPrimitive()
+	raw := w.data[start:w.pos]
+
+	var val any
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		val = s
+	case 't', 'f':
+		val = raw[0] == 't'
+	case 'n':
+		val = nil
+	default:
+		val = json.Number(raw)
+	}
+
+	changed := w.st.changed
+	w.st.changed = false
+
+	var (
+		res any
+		err error
+	)
+	if s, ok := val.(string); ok && w.j.maskRawFunc != nil && w.j.isStringMatched(w.st, k, fk, ignoreGlobal) {
+		res, err = w.rawString(fk, raw, s)
+	} else {
+		res, err = w.j.maskValue(w.st, k, fk, val, ignoreGlobal)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if w.st.changed {
+		b, err := json.Marshal(w.j.markMasked(k, fk, res))
+		if err != nil {
+			return err
+		}
+
+		w.replace(start, b)
+		w.st.original(fk, val)
+	}
+
+	w.st.changed = w.st.changed || changed
+	return nil
+}
+
+// rawString method for masking the string by xpath fk with MaskRawStringFunc receiving the raw token
+func (w *verbatim) rawString(fk string, raw []byte, val string) (any, error) {
+	res, err := w.j.maskRawFunc(fk, raw, val)
+	if errors.Is(err, ErrSkip) {
+		return val, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.st.changed = true
+	return res, nil
+}
+
+// decoded method for masking the whole object by fn after decoding it, it's used for objects with MaskObjectFunc
+// which could rewrite any of their keys
+func (w *verbatim) decoded(fn func(val any) (any, error)) error {
+	start := w.pos
+	w.skipValue()
+
+	val, err := w.j.unmarshal(w.data[start:w.pos])
+	if err != nil {
+		return err
+	}
+
+	res, err := fn(val)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	w.replace(start, b)
+	return nil
+}
+
+// replace method for replacing data from start to the current position with b
+func (w *verbatim) replace(start int, b []byte) {
+	w.out.Write(w.data[w.copied:start])
+	w.out.Write(b)
+	w.copied = w.pos
+}
+
+// skipSpace method for skipping whitespace
+func (w *verbatim) skipSpace() {
+	for w.pos < len(w.data) {
+		switch w.data[w.pos] {
+		case ' ', '\t', '\n', '\r':
+			w.pos++
+		default:
+			return
+		}
+	}
+}
+
+// skipString method for skipping string including quotes
+func (w *verbatim) skipString() {
+	w.pos++ // opening quote
+	for w.data[w.pos] != '"' {
+		if w.data[w.pos] == '\\' {
+			w.pos++
+		}
+		w.pos++
+	}
+	w.pos++ // closing quote
+}
+
+// skipPrimitive method for skipping string, number or literal value
+func (w *verbatim) skipPrimitive() {
+	if w.data[w.pos] == '"' {
+		w.skipString()
+		return
+	}
+
+	for w.pos < len(w.data) {
+		switch w.data[w.pos] {
+		case ',', ']', '}', ' ', '\t', '\n', '\r':
+			return
+		}
+		w.pos++
+	}
+}
+
+// skipArray method for skipping array, returns the number of its items
+func (w *verbatim) skipArray() int {
+	w.pos++ // [
+	w.skipSpace()
+	if w.data[w.pos] == ']' {
+		w.pos++
+		return 0
+	}
+
+	for n := 1; ; n++ {
+		w.skipValue()
+		w.skipSpace()
+		if w.data[w.pos] == ']' {
+			w.pos++
+			return n
+		}
+
+		w.pos++ // ,
+		w.skipSpace()
+	}
+}
+
+// skipValue method for skipping any value, nested objects and arrays are tracked by their depth
+func (w *verbatim) skipValue() {
+	depth := 0
+	for {
+		switch w.data[w.pos] {
+		case '{', '[':
+			depth++
+			w.pos++
+		case '}', ']':
+			depth--
+			w.pos++
+		case ' ', '\t', '\n', '\r', ',', ':':
+			w.pos++
+			continue
+		default:
+			w.skipPrimitive()
+		}
+
+		if depth == 0 {
+			return
+		}
+	}
+}
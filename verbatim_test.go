@@ -0,0 +1,117 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWithCopyUnmatchedVerbatim(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		mask    *JsonMask
+		expect  string
+		wantErr bool
+	}{
+		{
+			name:    "should rewrite only masked values",
+			mask:    NewJSONMaskWithOptions(WithFields("name", "/nested/ssn"), WithCopyUnmatchedVerbatim()),
+			value:   "{\"n\\u0061me\": \"a\", \"note\": \"caf\\u00e9 \\ud83d\\ude00 <b>\", \"big\": 12345678901234567890123,\n  \"f\" : 1.50e+10, \"nested\": { \"ssn\" : \"xy\" , \"arr\" : [ 1 , 2.0, [] ], \"ssn\": \"z\" } }\n",
+			expect:  "{\"n\\u0061me\": \"*\", \"note\": \"caf\\u00e9 \\ud83d\\ude00 <b>\", \"big\": 12345678901234567890123,\n  \"f\" : 1.50e+10, \"nested\": { \"ssn\" : \"**\" , \"arr\" : [ 1 , 2.0, [] ], \"ssn\": \"*\" } }\n",
+			wantErr: false,
+		},
+		{
+			name:    "should mask nested values of global field inside root array",
+			mask:    NewJSONMaskWithOptions(WithFields("user"), WithCopyUnmatchedVerbatim()),
+			value:   `[{"user": {"name": "ab", "tags": ["c", true, null]}, "ids": ["d"], "list": [1, [2, 3], {"a": "]"}]}, "e"]`,
+			expect:  `[{"user": {"name": "**", "tags": ["*", true, null]}, "ids": ["d"], "list": [1, [2, 3], {"a": "]"}]}, "e"]`,
+			wantErr: false,
+		},
+		{
+			name:    "should collapse arrays",
+			mask:    NewJSONMaskWithOptions(WithCollapseArrays("/list"), WithCopyUnmatchedVerbatim()),
+			value:   `{"list": [1, [2, 3], {"a": "]"}] , "b": 1.0}`,
+			expect:  `{"list": 3 , "b": 1.0}`,
+			wantErr: false,
+		},
+		{
+			name:    "should mask primitive root value",
+			mask:    NewJSONMaskWithOptions(WithFields("/"), WithCopyUnmatchedVerbatim()),
+			value:   ` "abc" `,
+			expect:  ` "***" `,
+			wantErr: false,
+		},
+		{
+			name:    "should return error on invalid json",
+			mask:    NewJSONMaskWithOptions(WithFields("a"), WithCopyUnmatchedVerbatim()),
+			value:   `{"a": "b"`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRegisterMaskRawStringFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should pass raw token with escapes to the func",
+			mask:   NewJSONMaskWithOptions(WithFields("a", "b"), WithCopyUnmatchedVerbatim()),
+			value:  `{"a": "\u0041\n", "b": "A", "c": "\u0041"}`,
+			expect: `{"a": "escaped:A\n", "b": "plain:A", "c": "\u0041"}`,
+		},
+		{
+			name:   "should keep string skipped by the func",
+			mask:   NewJSONMaskWithOptions(WithFields("a"), WithCopyUnmatchedVerbatim()),
+			value:  `{"a": "skip"}`,
+			expect: `{"a": "skip"}`,
+		},
+		{
+			name:   "should ignore the func without token walking",
+			mask:   NewJSONMaskWithOptions(WithFields("a")),
+			value:  `{"a":"A"}`,
+			expect: `{"a":"*"}`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+			tt.mask.RegisterMaskRawStringFunc(func(_ string, raw []byte, val string) (string, error) {
+				if val == "skip" {
+					return val, ErrSkip
+				}
+
+				if len(raw) != len(val)+2 {
+					return "escaped:" + val, nil
+				}
+				return "plain:" + val, nil
+			})
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
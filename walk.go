@@ -0,0 +1,417 @@
+package jsonmask
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// truncatedMarker is the last item of arrays truncated by WithTruncateArrays
+const truncatedMarker = "...(+%d more)"
+
+// frame is an object or array on the traversal stack, the traversal is iterative so deeply nested
+// documents don't grow the goroutine stack
+type frame struct {
+	// k is the field key of array items, pk is the xpath of the object or array
+	k, pk        string
+	ignoreGlobal bool
+
+	m    map[string]any
+	keys []string
+	o    orderedObject
+	sl   []any
+	// targets are keys of the object members masked by WithDiscriminatorRule
+	targets map[string]struct{}
+
+	// next is the index of the next item to mask
+	next int
+	// parent is the frame of the object or array holding this one at the slot index
+	parent *frame
+	slot   int
+	// wrapper is set for the map wrapping the primitive root value, it isn't an object of the document
+	wrapper bool
+	// ptr is the map or slice tracked in state ancestors, 0 if it's not tracked
+	ptr uintptr
+	// removed are indexes of the ordered object members moved by WithRedactedBlock
+	removed []int
+	// depth is the number of objects and arrays holding the object or array of the frame
+	depth int
+	// pointer is JSON Pointer of the object or array, it's set only by MaskAsPatch
+	pointer string
+	// pooled is set for frames of nested values got from the pool by WithPooledDecoding
+	pooled bool
+	// truncated is the number of array items dropped by WithTruncateArrays
+	truncated int
+	// shallow is set for objects of WithShallowFields, their nested objects and arrays keep ignoreGlobal
+	shallow bool
+	// renamed is set for objects with keys masked by RegisterMaskKeyFunc
+	renamed bool
+}
+
+// newFrame method for creating frame of the object or array val by xpath pk, maps and non-empty slices
+// are tracked in state ancestors for the cycle detection until the frame is done
+func (j *JsonMask) newFrame(st *state, k, pk string, val any, ignoreGlobal bool) (*frame, error) {
+	return j.initFrame(&frame{}, st, k, pk, val, ignoreGlobal)
+}
+
+// initFrame method for initializing the empty frame fr like newFrame
+func (j *JsonMask) initFrame(fr *frame, st *state, k, pk string, val any, ignoreGlobal bool) (*frame, error) {
+	fr.k, fr.pk, fr.ignoreGlobal, fr.targets, fr.depth = k, pk, ignoreGlobal, j.objectTargets(val), st.depth
+
+	switch v := val.(type) {
+	case map[string]any:
+		fr.m = v
+		if cap(fr.keys) < len(v) {
+			fr.keys = make([]string, 0, len(v))
+		}
+		for key := range v {
+			fr.keys = append(fr.keys, key)
+		}
+
+		if j.sortedKeys {
+			sort.Strings(fr.keys)
+		}
+
+		if st.ancestors != nil {
+			fr.ptr = reflect.ValueOf(v).Pointer()
+		}
+	case orderedObject:
+		fr.o = v
+	case []any:
+		if err := j.checkArrayTypes(pk, v); err != nil {
+			return nil, err
+		}
+
+		fr.sl = v
+		if st.ancestors != nil && len(v) > 0 {
+			fr.ptr = reflect.ValueOf(v).Pointer()
+		}
+
+		if limit, ok := j.truncateArrays[j.pathKey(pk)]; ok && len(v) > limit {
+			fr.sl, fr.truncated = v[:limit:limit], len(v)-limit
+			st.changed = true
+		}
+	}
+
+	if fr.ptr != 0 {
+		if err := st.enter(fr.ptr, pk); err != nil {
+			return nil, err
+		}
+	}
+
+	return fr, nil
+}
+
+// len method for getting the number of the frame items
+func (fr *frame) len() int {
+	switch {
+	case fr.m != nil:
+		return len(fr.keys)
+	case fr.o != nil:
+		return len(fr.o)
+	default:
+		return len(fr.sl)
+	}
+}
+
+// item method for getting key, xpath and value of the next item
+func (fr *frame) item() (string, string, any) {
+	switch {
+	case fr.m != nil:
+		k := fr.keys[fr.next]
+		return k, fr.pk + pathKey + k, fr.m[k]
+	case fr.o != nil:
+		m := fr.o[fr.next]
+		return m.key, fr.pk + pathKey + m.key, m.value
+	default:
+		return fr.k, fmt.Sprintf("%s[%d]", fr.pk, fr.next), fr.sl[fr.next]
+	}
+}
+
+// itemPointer method for getting JSON Pointer of the next item, the primitive root value is the whole document
+func (fr *frame) itemPointer() string {
+	switch {
+	case fr.wrapper:
+		return ""
+	case fr.m != nil:
+		return fr.pointer + pathKey + pointerEscaper.Replace(fr.keys[fr.next])
+	case fr.o != nil:
+		return fr.pointer + pathKey + pointerEscaper.Replace(fr.o[fr.next].key)
+	default:
+		return fr.pointer + pathKey + strconv.Itoa(fr.next)
+	}
+}
+
+// set method for replacing the next item with the masked value and moving to the following one
+func (fr *frame) set(val any) {
+	fr.setAt(fr.next, val)
+	fr.next++
+}
+
+// setAt method for replacing the item by index i with the value
+func (fr *frame) setAt(i int, val any) {
+	switch {
+	case fr.m != nil:
+		fr.m[fr.keys[i]] = val
+	case fr.o != nil:
+		fr.o[i].value = val
+	default:
+		fr.sl[i] = val
+	}
+}
+
+// remove method for deleting the next item of the object and moving to the following one,
+// ordered object members are deleted once all of them are masked
+func (fr *frame) remove() {
+	switch {
+	case fr.m != nil:
+		delete(fr.m, fr.keys[fr.next])
+	case fr.o != nil:
+		fr.removed = append(fr.removed, fr.next)
+	}
+	fr.next++
+}
+
+// compact method for deleting removed members of the ordered object, it replaces the one in the parent frame
+func (fr *frame) compact() {
+	if len(fr.removed) == 0 {
+		return
+	}
+
+	o := make(orderedObject, 0, len(fr.o)-len(fr.removed))
+	for i, m := range fr.o {
+		if len(fr.removed) > 0 && fr.removed[0] == i {
+			fr.removed = fr.removed[1:]
+			continue
+		}
+		o = append(o, m)
+	}
+
+	fr.o = o
+	if fr.parent != nil {
+		fr.parent.setAt(fr.slot, o)
+	}
+}
+
+// value method for getting the object or array of the frame
+func (fr *frame) value() any {
+	switch {
+	case fr.m != nil:
+		return fr.m
+	case fr.o != nil:
+		return fr.o
+	default:
+		return fr.sl
+	}
+}
+
+// walk method for masking items of the object or array val by xpath pk, returns the masked value
+func (j *JsonMask) walk(st *state, k, pk string, val any, ignoreGlobal bool) (any, error) {
+	fr, err := j.newFrame(st, k, pk, val, ignoreGlobal)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := j.traverse(st, fr); err != nil {
+		return nil, err
+	}
+
+	return fr.value(), nil
+}
+
+// traverse method for masking items of the frame and all nested objects and arrays depth-first in the same
+// order the recursive traversal would, nested values are pushed on the stack instead of the recursive call
+func (j *JsonMask) traverse(st *state, root *frame) error {
+	if root.sl != nil {
+		if err := j.enterArray(st, root.pk); err != nil {
+			return err
+		}
+	}
+
+	stack := []*frame{root}
+	for len(stack) > 0 {
+		fr := stack[len(stack)-1]
+		if fr.next == fr.len() {
+			if fr.ptr != 0 {
+				st.leave(fr.ptr)
+			}
+
+			if fr.sl != nil {
+				j.shuffleArray(st, fr.pk, fr.sl)
+				st.arrays--
+				fr.truncate()
+			}
+
+			fr.compact()
+
+			if err := j.maskDone(st, fr); err != nil {
+				return err
+			}
+
+			if err := j.maskKeys(st, fr); err != nil {
+				return err
+			}
+
+			if st.patch != nil && j.isReplaced(fr) {
+				st.patch = append(st.patch, PatchOp{Op: "replace", Path: fr.pointer, Value: fr.value()})
+			}
+
+			stack = stack[:len(stack)-1]
+			if fr != root {
+				releaseFrame(fr)
+			}
+			continue
+		}
+
+		changed := st.changed
+		st.changed = false
+
+		k, fk, val := fr.item()
+		st.depth = fr.depth + 1
+		ignoreGlobal := fr.ignoreGlobal || (fr.shallow && isContainer(val))
+		if _, ok := fr.targets[j.fold(k)]; ok {
+			ignoreGlobal = false
+		}
+
+		res, child, err := j.maskNode(st, k, fk, val, ignoreGlobal)
+		if err != nil {
+			return err
+		}
+
+		var pointer string
+		if st.patch != nil {
+			pointer = fr.itemPointer()
+		}
+
+		moved := false
+		if child == nil && st.changed {
+			st.original(fk, val)
+			res = j.markMasked(k, fk, res)
+			moved = j.moveRedacted(st, fr, k, fk, res)
+			if st.patch != nil {
+				st.patch = append(st.patch, PatchOp{Op: "replace", Path: pointer, Value: res})
+			}
+		}
+		st.changed = st.changed || changed
+
+		if moved {
+			fr.remove()
+			continue
+		}
+
+		fr.set(res)
+		if child == nil {
+			continue
+		}
+
+		if child.sl != nil {
+			if err := j.enterArray(st, child.pk); err != nil {
+				return err
+			}
+		}
+
+		child.parent, child.slot, child.pointer = fr, fr.next-1, pointer
+		stack = append(stack, child)
+	}
+
+	return nil
+}
+
+// isContainer check value on being an object or array
+func isContainer(val any) bool {
+	switch val.(type) {
+	case map[string]any, orderedObject, []any:
+		return true
+	default:
+		return false
+	}
+}
+
+// isShallow method for checking that the object of field k is masked only at its own level by WithShallowFields,
+// ignoreGlobal is of the object and ignoreGlobalVal is of its members
+func (j *JsonMask) isShallow(k string, ignoreGlobal, ignoreGlobalVal bool) bool {
+	if !ignoreGlobal || ignoreGlobalVal {
+		return false
+	}
+
+	_, ok := j.shallowFields[j.fold(k)]
+	return ok
+}
+
+// shuffleArray method for shuffling masked items of the array by xpath pk by WithShuffleArrays,
+// the permutation depends on the seed and the xpath only
+func (j *JsonMask) shuffleArray(st *state, pk string, sl []any) {
+	if _, ok := j.shuffleArrays[j.pathKey(pk)]; !ok || len(sl) < 2 {
+		return
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(pk))
+	rnd := rand.New(rand.NewSource(j.shuffleSeed ^ int64(h.Sum64())))
+	rnd.Shuffle(len(sl), func(a, b int) { sl[a], sl[b] = sl[b], sl[a] })
+
+	st.changed = true
+}
+
+// truncate method for appending the marker of items dropped by WithTruncateArrays to the array of the frame,
+// it replaces the one in the parent frame. MaskAsPatch replaces the whole array
+func (fr *frame) truncate() {
+	if fr.truncated == 0 {
+		return
+	}
+
+	fr.sl = append(fr.sl, fmt.Sprintf(truncatedMarker, fr.truncated))
+	if fr.parent != nil {
+		fr.parent.setAt(fr.slot, fr.sl)
+	}
+}
+
+// isReplaced method for checking that the object or array of the frame is changed as a whole by object funcs,
+// key masking, WithShuffleArrays or WithTruncateArrays, MaskAsPatch replaces it with one operation
+func (j *JsonMask) isReplaced(fr *frame) bool {
+	if fr.wrapper {
+		return false
+	}
+
+	if fr.truncated > 0 || fr.renamed {
+		return true
+	}
+
+	if fr.sl != nil {
+		_, ok := j.shuffleArrays[j.pathKey(fr.pk)]
+		return ok && len(fr.sl) > 1
+	}
+
+	path := j.pathKey(rootPath(fr.pk))
+	_, before := j.objectFuncs[path]
+	_, after := j.maskedObjectFuncs[path]
+	return before || after
+}
+
+// maskDone method for calling MaskObjectFunc registered by RegisterMaskedObjectFunc for the object of the frame
+// after its fields are masked, the changed ordered object replaces the one in the parent frame
+func (j *JsonMask) maskDone(st *state, fr *frame) error {
+	if len(j.maskedObjectFuncs) == 0 || fr.wrapper {
+		return nil
+	}
+
+	switch {
+	case fr.m != nil:
+		return j.maskObjectFunc(st, j.maskedObjectFuncs, fr.pk, fr.m)
+	case fr.o != nil:
+		o, err := j.maskOrderedObjectFunc(st, j.maskedObjectFuncs, fr.pk, fr.o)
+		if err != nil {
+			return err
+		}
+
+		fr.o = o
+		if fr.parent != nil {
+			fr.parent.setAt(fr.slot, o)
+		}
+	}
+
+	return nil
+}
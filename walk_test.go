@@ -0,0 +1,176 @@
+package jsonmask
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestDeeplyNestedDocument(t *testing.T) {
+	const depth = 5000
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask field beneath thousands of nested objects",
+			mask:   NewJSONMask("ssn"),
+			value:  strings.Repeat(`{"n":`, depth) + `{"ssn":"123","name":"bob"}` + strings.Repeat(`}`, depth),
+			expect: strings.Repeat(`{"n":`, depth) + `{"name":"bob","ssn":"***"}` + strings.Repeat(`}`, depth),
+		},
+		{
+			name:   "should mask field beneath thousands of nested arrays",
+			mask:   NewJSONMask("ssn"),
+			value:  strings.Repeat(`[`, depth) + `{"ssn":"123"}` + strings.Repeat(`]`, depth),
+			expect: strings.Repeat(`[`, depth) + `{"ssn":"***"}` + strings.Repeat(`]`, depth),
+		},
+		{
+			name:   "should mask field beneath thousands of nested ordered objects",
+			mask:   NewJSONMaskWithOptions(WithGlobalFields("ssn"), WithOrderedKeys()),
+			value:  strings.Repeat(`{"n":[`, depth/2) + `{"ssn":"123","name":"bob"}` + strings.Repeat(`]}`, depth/2),
+			expect: strings.Repeat(`{"n":[`, depth/2) + `{"ssn":"***","name":"bob"}` + strings.Repeat(`]}`, depth/2),
+		},
+		{
+			name:   "should mask field beneath thousands of nested values with verbatim copy",
+			mask:   NewJSONMaskWithOptions(WithFields("ssn"), WithCopyUnmatchedVerbatim()),
+			value:  strings.Repeat(`{"n": [`, depth/2) + `{"ssn": "123"}` + strings.Repeat(`]}`, depth/2),
+			expect: strings.Repeat(`{"n": [`, depth/2) + `{"ssn": "***"}` + strings.Repeat(`]}`, depth/2),
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.Mask(tt.value)
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %.100v..., want %.100v...", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestDeeplyNestedAny(t *testing.T) {
+	// the stack limit makes a recursive copying or walk of the nesting overflow, JSON input can't be nested
+	// so deep because of the nesting limit of the decoder
+	defer debug.SetMaxStack(debug.SetMaxStack(512 << 10))
+
+	const depth = 10000
+
+	build := func() any {
+		var val any = map[string]any{"ssn": "123"}
+		for i := 0; i < depth; i++ {
+			if i%2 == 0 {
+				val = []any{val}
+			} else {
+				val = map[string]any{"n": val}
+			}
+		}
+		return map[string]any{"n": val}
+	}
+
+	tests := []struct {
+		name   string
+		mask   *JsonMask
+		leaf   func(val any) any
+		expect any
+	}{
+		{
+			name: "should copy and mask field beneath deeply nested maps and slices",
+			mask: NewJSONMask("ssn"),
+			leaf: func(val any) any {
+				for {
+					switch v := val.(type) {
+					case map[string]any:
+						if ssn, ok := v["ssn"]; ok {
+							return ssn
+						}
+						val = v["n"]
+					case []any:
+						val = v[0]
+					}
+				}
+			},
+			expect: "***",
+		},
+		{
+			name:   "should hash deeply nested subtree",
+			mask:   NewJSONMaskWithOptions(WithHashSubtree("/n")),
+			leaf:   func(val any) any { return len(val.(map[string]any)["n"].(string)) },
+			expect: sha256.Size * 2,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			tt.mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+			got, err := tt.mask.MaskAnyCopy(build())
+			if err != nil {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+
+			if leaf := tt.leaf(got); leaf != tt.expect {
+				t.Errorf("Process() got = %v, want %v", leaf, tt.expect)
+			}
+		})
+	}
+}
+
+func TestDeeplyNestedOrderedDecoding(t *testing.T) {
+	// the stack limit makes a recursive decoding of the nesting overflow
+	defer debug.SetMaxStack(debug.SetMaxStack(512 << 10))
+
+	const depth = 4000
+
+	value := strings.Repeat(`{"n":[`, depth) + `{"ssn":"123","name":"bob"}` + strings.Repeat(`]}`, depth)
+	got, err := decodeOrdered([]byte(value))
+	if err != nil {
+		t.Fatalf("Process() error = %v, wantErr %v", err, false)
+	}
+
+	for i := 0; i < depth; i++ {
+		o, ok := got.(orderedObject)
+		if !ok || len(o) != 1 || o[0].key != "n" {
+			t.Fatalf("Process() got = %.100v at depth %d, want object of n", got, i)
+		}
+		sl, ok := o[0].value.([]any)
+		if !ok || len(sl) != 1 {
+			t.Fatalf("Process() got = %.100v at depth %d, want array of one item", o[0].value, i)
+		}
+		got = sl[0]
+	}
+
+	expect := orderedObject{{key: "ssn", value: "123"}, {key: "name", value: "bob"}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}
+
+func TestDeeplyNestedCycle(t *testing.T) {
+	root := map[string]any{}
+	m := root
+	for i := 0; i < 5000; i++ {
+		child := map[string]any{}
+		m["n"] = child
+		m = child
+	}
+	m["n"] = root
+
+	mask := NewJSONMask("ssn")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	if _, err := mask.MaskAny(root); !errors.Is(err, ErrCycle) {
+		t.Errorf("Process() error = %v, want %v", err, ErrCycle)
+	}
+}
@@ -0,0 +1,39 @@
+package jsonmask
+
+import (
+	"bytes"
+	"io"
+)
+
+// maskingWriter is an io.Writer which masks JSON documents before writing them to the wrapped writer
+type maskingWriter struct {
+	mask *JsonMask
+	w    io.Writer
+}
+
+// Writer method for wrapping io.Writer with masking, every Write call must contain one complete
+// JSON document (as log libraries write one entry per call), a trailing newline is kept.
+// Documents that can't be masked are not written and the error is returned
+func (j *JsonMask) Writer(w io.Writer) io.Writer {
+	return &maskingWriter{mask: j, w: w}
+}
+
+// Write method for masking p as one JSON document and writing it through
+func (mw *maskingWriter) Write(p []byte) (int, error) {
+	doc := bytes.TrimRight(p, "\r\n")
+
+	out, err := mw.mask.MaskBytes(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(doc) != len(p) {
+		out = append(out[:len(out):len(out)], p[len(doc):]...)
+	}
+
+	if _, err = mw.w.Write(out); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,43 @@
+package jsonmask
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	mask := NewJSONMask("password")
+	mask.RegisterMaskStringFunc(MaskFilledString("*"))
+
+	var buf bytes.Buffer
+	w := mask.Writer(&buf)
+
+	lines := []string{
+		`{"level": "info", "password": "secret"}` + "\n",
+		`{"level": "warn", "user": {"password": "pass"}}` + "\n",
+		`{"level": "debug"}`,
+	}
+	for _, line := range lines {
+		n, err := w.Write([]byte(line))
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if n != len(line) {
+			t.Errorf("Write() n = %v, want %v", n, len(line))
+		}
+	}
+
+	expect := `{"level":"info","password":"******"}` + "\n" +
+		`{"level":"warn","user":{"password":"****"}}` + "\n" +
+		`{"level": "debug"}`
+	if buf.String() != expect {
+		t.Errorf("Write() got = %v, want %v", buf.String(), expect)
+	}
+
+	if _, err := w.Write([]byte(`{"password": `)); err == nil {
+		t.Errorf("Write() error = nil, want error for invalid document")
+	}
+	if buf.String() != expect {
+		t.Errorf("Write() got = %v, want invalid document not written", buf.String())
+	}
+}
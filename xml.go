@@ -0,0 +1,65 @@
+package jsonmask
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskXMLText masks text content of the elements with the passed local names (and of their nested elements)
+// inside XML value, the text is filled with "*" by decoded length and the rest of the markup including
+// attributes is kept as is. Values that aren't well-formed XML are left unchanged
+func MaskXMLText(elementNames ...string) MaskStringFunc {
+	names := make(map[string]struct{}, len(elementNames))
+	for _, name := range elementNames {
+		names[name] = struct{}{}
+	}
+
+	return func(_, val string) (string, error) {
+		var (
+			b      strings.Builder
+			copied int64
+			depth  int
+		)
+
+		dec := xml.NewDecoder(strings.NewReader(val))
+		for {
+			start := dec.InputOffset()
+			tok, err := dec.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return val, ErrSkip
+			}
+
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if _, ok := names[t.Name.Local]; ok || depth > 0 {
+					depth++
+				}
+			case xml.EndElement:
+				if depth > 0 {
+					depth--
+				}
+			case xml.CharData:
+				if depth == 0 || strings.TrimSpace(string(t)) == "" {
+					continue
+				}
+
+				b.WriteString(val[copied:start])
+				b.WriteString(strings.Repeat("*", utf8.RuneCount(t)))
+				copied = dec.InputOffset()
+			}
+		}
+
+		if copied == 0 {
+			return val, ErrSkip
+		}
+
+		b.WriteString(val[copied:])
+		return b.String(), nil
+	}
+}
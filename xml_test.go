@@ -0,0 +1,67 @@
+package jsonmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaskXMLText(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect string
+	}{
+		{
+			name:   "should mask text of named element",
+			value:  `<user><name>bob</name><ssn>123-45</ssn></user>`,
+			expect: `<user><name>bob</name><ssn>******</ssn></user>`,
+		},
+		{
+			name:   "should mask text of elements nested in named element",
+			value:  "<card>\n  <number>4111</number>\n  <cvv>&lt;1&gt;</cvv>\n</card><note>ok</note>",
+			expect: "<card>\n  <number>****</number>\n  <cvv>***</cvv>\n</card><note>ok</note>",
+		},
+		{
+			name:   "should mask every occurrence of named element",
+			value:  `<list><ssn>1</ssn><a><ssn>22</ssn></a></list>`,
+			expect: `<list><ssn>*</ssn><a><ssn>**</ssn></a></list>`,
+		},
+		{
+			name:   "should keep attribute with the same name as element",
+			value:  `<user ssn="123"><name>bob</name></user>`,
+			expect: `<user ssn="123"><name>bob</name></user>`,
+		},
+		{
+			name:   "should keep invalid xml unchanged",
+			value:  `<user><ssn>123</user>`,
+			expect: `<user><ssn>123</user>`,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("#%d:%s", i, tt.name), func(t *testing.T) {
+			got, err := MaskXMLText("ssn", "card")("", tt.value)
+			if err != nil && err != ErrSkip {
+				t.Errorf("Process() error = %v, wantErr %v", err, false)
+				return
+			}
+			if got != tt.expect {
+				t.Errorf("Process() got = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMaskXMLTextInJSON(t *testing.T) {
+	mask := NewJSONMask("payload")
+	mask.RegisterMaskStringFunc(MaskXMLText("ssn"))
+
+	got, err := mask.Mask(`{"payload":"<user id=\"1\"><ssn>123</ssn></user>","other":"<ssn>1</ssn>"}`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	expect := `{"other":"\u003cssn\u003e1\u003c/ssn\u003e","payload":"\u003cuser id=\"1\"\u003e\u003cssn\u003e***\u003c/ssn\u003e\u003c/user\u003e"}`
+	if got != expect {
+		t.Errorf("Process() got = %v, want %v", got, expect)
+	}
+}